@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"go.uber.org/zap/zaptest"
+)
+
+// startServe runs Serve on a free local port with a handler that takes delay to respond, and returns the
+// port's URL and Serve's result
+func startServe(t *testing.T, ctx context.Context, delay time.Duration) (string, <-chan error) {
+	t.Helper()
+
+	// Reserve a free port for the listener that Serve creates
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := reserved.Addr().String()
+	reserved.Close()
+
+	prevLogger, prevRouter, prevListen, prevTimeout := logger, router, listenFlag, shutdownTimeoutFlag
+	t.Cleanup(func() {
+		logger, router, listenFlag, shutdownTimeoutFlag = prevLogger, prevRouter, prevListen, prevTimeout
+	})
+
+	logger = zaptest.NewLogger(t)
+	listenFlag = addr
+	shutdownTimeoutFlag = 2 * time.Second
+
+	router = http.NewServeMux()
+	router.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		io.WriteString(w, "bitcoind_up 1\n")
+	})
+
+	errs := make(chan error, 1)
+	go func() { errs <- Serve(ctx) }()
+
+	// Wait for the listener
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Serve is not listening on %s: %v", addr, err)
+		}
+	}
+
+	return "http://" + addr + "/metrics", errs
+}
+
+// assertShutdown fails the test if Serve does not return nil within the shutdown timeout
+func assertShutdown(t *testing.T, errs <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	case <-time.After(shutdownTimeoutFlag):
+		t.Fatalf("Serve did not return within the shutdown timeout of %s", shutdownTimeoutFlag)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url, errs := startServe(t, ctx, 0)
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	cancel()
+	assertShutdown(t, errs)
+
+	if _, err := http.Get(url); err == nil {
+		t.Error("listener accepted a request after shutdown")
+	}
+}
+
+func TestServeShutdownSignal(t *testing.T) {
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+
+	url, errs := startServe(t, ctx, 500*time.Millisecond)
+
+	// A scrape that is in flight when the signal arrives is completed
+	scraped := make(chan error, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err == nil {
+			_, err = io.ReadAll(res.Body)
+			res.Body.Close()
+		}
+
+		scraped <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	assertShutdown(t, errs)
+
+	if err := <-scraped; err != nil {
+		t.Errorf("in-flight scrape failed: %v", err)
+	}
+}
+
+func TestTLSProxy(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tlsProxyTokenHeader) != "" {
+			t.Error("proxy token was forwarded to the RPC server")
+		}
+
+		io.WriteString(w, `{"result":null,"error":null,"id":1}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	prevLogger, prevConfig := logger, config
+	t.Cleanup(func() { logger, config = prevLogger, prevConfig })
+
+	logger = zaptest.NewLogger(t)
+	config = rpcclient.ConnConfig{Host: strings.TrimPrefix(upstream.URL, "https://"), ExtraHeaders: map[string]string{"X-Extra": "1"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := TLSProxy(ctx, &tls.Config{InsecureSkipVerify: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !config.DisableTLS || config.ExtraHeaders["X-Extra"] != "1" || config.ExtraHeaders[tlsProxyTokenHeader] == "" {
+		t.Fatalf("RPC client configuration was not updated: %+v", config)
+	}
+
+	post := func(token string) (int, error) {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+config.Host, strings.NewReader(`{"method":"ping"}`))
+		if token != "" {
+			req.Header.Set(tlsProxyTokenHeader, token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+
+		return res.StatusCode, nil
+	}
+
+	if status, err := post(""); err != nil || status != http.StatusUnauthorized {
+		t.Errorf("request without the proxy token: status %d, error %v", status, err)
+	}
+
+	if status, err := post("guess"); err != nil || status != http.StatusUnauthorized {
+		t.Errorf("request with a wrong proxy token: status %d, error %v", status, err)
+	}
+
+	if status, err := post(config.ExtraHeaders[tlsProxyTokenHeader]); err != nil || status != http.StatusOK {
+		t.Errorf("request with the proxy token: status %d, error %v", status, err)
+	}
+
+	// The listener is closed when the context is done
+	cancel()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", config.Host)
+		if err != nil {
+			break
+		}
+		conn.Close()
+
+		if time.Now().After(deadline) {
+			t.Fatal("TLS proxy is still listening after its context was canceled")
+		}
+	}
+}
+
+func TestWatchdogProbe(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	socket, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { socket.Close() })
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", "200000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	prevLogger, prevClient := logger, client
+	t.Cleanup(func() { logger, client = prevLogger, prevClient })
+
+	logger = zaptest.NewLogger(t)
+	client = server.Client(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		Watchdog(ctx)
+		close(done)
+	}()
+
+	// No collector runs, so pings are only sent after the watchdog checks the node itself
+	socket.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err := socket.Read(buf)
+	if err != nil {
+		t.Fatalf("no watchdog ping: %v", err)
+	}
+
+	if state := string(buf[:n]); state != "WATCHDOG=1" {
+		t.Errorf("notified %q, expected WATCHDOG=1", state)
+	}
+
+	if calls := server.Calls("getblockchaininfo"); calls == 0 {
+		t.Error("watchdog did not call getblockchaininfo")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,205 @@
+package bitcoind
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// Alerts raised by AlertWatcher
+const (
+	AlertReorg                = "reorg"
+	AlertNoBlock              = "no_block"
+	AlertInitialBlockDownload = "initial_block_download"
+	AlertLowPeers             = "low_peers"
+)
+
+// Alert states. Reorgs are events, which are only reported as firing
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// maxReorgDepth limits the number of disconnected blocks that are walked to measure a reorg
+const maxReorgDepth = 100
+
+// AlertThresholds configure the conditions for which AlertWatcher raises alerts. Zero values disable a condition.
+// Reorgs and initial block download are always reported
+type AlertThresholds struct {
+	// BlockAge is the time since the best block's timestamp after which no_block fires
+	BlockAge time.Duration
+
+	// MinPeers is the number of connected peers below which low_peers fires
+	MinPeers int64
+}
+
+// Alert is a change in the state of an alert condition
+type Alert struct {
+	Name    string            `json:"alert"`
+	State   string            `json:"state"`
+	Chain   string            `json:"chain"`
+	Summary string            `json:"summary"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Text formats the alert as a single line message, with its labels to identify the node
+func (alert Alert) Text() string {
+	text := fmt.Sprintf("[%s] %s on %s: %s", strings.ToUpper(alert.State), alert.Name, alert.Chain, alert.Summary)
+
+	labels := make([]string, 0, len(alert.Labels))
+	for name, value := range alert.Labels {
+		labels = append(labels, name+"="+value)
+	}
+
+	if len(labels) > 0 {
+		sort.Strings(labels)
+		text += " (" + strings.Join(labels, ", ") + ")"
+	}
+
+	return text
+}
+
+// NewAlertWatcher creates an AlertWatcher for the node's state
+func NewAlertWatcher(client *rpcclient.Client, thresholds AlertThresholds, options ...Option) *AlertWatcher {
+	return &AlertWatcher{
+		Client:     client,
+		Options:    NewOptions(options...),
+		Thresholds: thresholds,
+
+		firing: map[string]bool{},
+	}
+}
+
+// AlertWatcher detects alert conditions by polling the node, and reports each condition when it starts and when
+// it resolves. Conditions that are present when the watcher starts are reported on its first poll
+type AlertWatcher struct {
+	*rpcclient.Client
+	Options
+
+	Thresholds AlertThresholds
+
+	// tip is the best block of the last poll, which a reorg disconnects
+	tip *btcjson.GetBlockHeaderVerboseResult
+
+	firing map[string]bool
+}
+
+// Poll reads the node's state and returns the alerts whose state changed since the last poll
+func (w *AlertWatcher) Poll() ([]Alert, error) {
+	chain, err := w.BlockChainInfo(w.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := w.BlockHeader(w.Client, chain.BestBlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var alerts []Alert
+
+	alert := func(name, state, summary string) {
+		alerts = append(alerts, Alert{name, state, chain.Chain, summary, w.ConstLabels, now})
+	}
+
+	condition := func(name string, active bool, firing, resolved string) {
+		if active == w.firing[name] {
+			return
+		}
+
+		w.firing[name] = active
+		if active {
+			alert(name, AlertFiring, firing)
+		} else {
+			alert(name, AlertResolved, resolved)
+		}
+	}
+
+	if w.tip != nil && w.tip.Hash != header.Hash {
+		depth, fork, err := w.disconnected(w.tip)
+		if err != nil {
+			return nil, err
+		}
+
+		if depth > 0 {
+			alert(AlertReorg, AlertFiring, fmt.Sprintf("%d blocks after height %d were replaced, new best block %d %s", depth, fork, header.Height, header.Hash))
+		}
+	}
+
+	w.tip = header
+
+	condition(AlertInitialBlockDownload, chain.InitialBlockDownload,
+		fmt.Sprintf("node is in initial block download at height %d of %d headers", chain.Blocks, chain.Headers),
+		fmt.Sprintf("node finished initial block download at height %d", chain.Blocks))
+
+	if w.Thresholds.BlockAge > 0 {
+		age := now.Sub(time.Unix(header.Time, 0)).Truncate(time.Second)
+
+		condition(AlertNoBlock, age > w.Thresholds.BlockAge,
+			fmt.Sprintf("no block for %s, best block %d %s", age, header.Height, header.Hash),
+			fmt.Sprintf("best block %d %s is %s old", header.Height, header.Hash, age))
+	}
+
+	// The REST interface does not report connections, so peers are only watched over RPC
+	if w.Thresholds.MinPeers > 0 && w.REST == nil {
+		peers, err := w.GetConnectionCount()
+		if err != nil {
+			return alerts, err
+		}
+
+		condition(AlertLowPeers, peers < w.Thresholds.MinPeers,
+			fmt.Sprintf("%d connected peers, below the minimum of %d", peers, w.Thresholds.MinPeers),
+			fmt.Sprintf("%d connected peers", peers))
+	}
+
+	return alerts, nil
+}
+
+// disconnected returns the number of blocks from tip that are no longer in the active chain, and the height of
+// the last block that still is. Deep reorgs are only counted up to maxReorgDepth
+func (w *AlertWatcher) disconnected(tip *btcjson.GetBlockHeaderVerboseResult) (int, int32, error) {
+	header, err := w.BlockHeader(w.Client, tip.Hash)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	depth := 0
+	for header.Confirmations < 0 && header.PreviousHash != "" && depth < maxReorgDepth {
+		depth++
+
+		header, err = w.BlockHeader(w.Client, header.PreviousHash)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return depth, header.Height, nil
+}
+
+// Run polls the node every interval until the context is canceled, passing changed alerts to notify. The node is
+// polled when Run starts, so that conditions that are already present are reported. Errors are passed to onError
+func (w *AlertWatcher) Run(ctx context.Context, interval time.Duration, notify func(Alert), onError func(error)) {
+	for {
+		alerts, err := w.Poll()
+		for _, alert := range alerts {
+			notify(alert)
+		}
+
+		if err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package bitcoind
+
+import (
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AncestryCountBuckets are histogram bucket bounds for ancestor and descendant counts. The default
+// -limitancestorcount and -limitdescendantcount policies are 25
+var AncestryCountBuckets = []float64{1, 2, 3, 5, 10, 15, 20, 25}
+
+// AncestrySizeBuckets are histogram bucket bounds for ancestor and descendant virtual sizes. The default
+// -limitancestorsize and -limitdescendantsize policies are 101 kvB
+var AncestrySizeBuckets = []float64{250, 500, 1000, 2500, 5000, 10000, 25000, 50000, 101000}
+
+// NewMempoolAncestryDescriptors creates descriptors for collected mempool ancestry metrics
+func NewMempoolAncestryDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_ancestor_count"), "Number of in-mempool ancestors of each mempool transaction, including itself", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_descendant_count"), "Number of in-mempool descendants of each mempool transaction, including itself", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_mempool_ancestor_size", "bitcoind_mempool_ancestor_vsize_bytes"), "Virtual size of in-mempool ancestors of each mempool transaction, including itself", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_mempool_descendant_size", "bitcoind_mempool_descendant_vsize_bytes"), "Virtual size of in-mempool descendants of each mempool transaction, including itself", []string{"chain"}, opts.ConstLabels),
+	}
+}
+
+// NewMempoolAncestryCollector creates a new prometheus.Collector for ancestor and descendant properties in verbose getrawmempool responses
+func NewMempoolAncestryCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &MempoolAncestryCollector{client, logger, opts, NewMempoolAncestryDescriptors(opts)}
+}
+
+// MempoolAncestryCollector builds histograms of transaction chaining from verbose getrawmempool RPC responses. The
+// full mempool is decoded on every collection, so this collector is more expensive than the MempoolCollector
+type MempoolAncestryCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *MempoolAncestryCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getrawmempool RPC and builds histograms from its entries
+func (col *MempoolAncestryCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	entries, err := col.RawMempoolVerbose(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getrawmempool", err)
+		return
+	}
+
+	LastCollection.Mark()
+
+	ancestorCount := NewHistogram(AncestryCountBuckets)
+	descendantCount := NewHistogram(AncestryCountBuckets)
+	ancestorSize := NewHistogram(AncestrySizeBuckets)
+	descendantSize := NewHistogram(AncestrySizeBuckets)
+
+	for _, entry := range entries {
+		ancestorCount.Observe(float64(entry.AncestorCount))
+		descendantCount.Observe(float64(entry.DescendantCount))
+		ancestorSize.Observe(float64(entry.AncestorSize))
+		descendantSize.Observe(float64(entry.DescendantSize))
+	}
+
+	metric, _ := ancestorCount.Metric(col.Descriptors[0], chain.Chain)
+	out <- metric
+
+	metric, _ = descendantCount.Metric(col.Descriptors[1], chain.Chain)
+	out <- metric
+
+	metric, _ = ancestorSize.Metric(col.Descriptors[2], chain.Chain)
+	out <- metric
+
+	metric, _ = descendantSize.Metric(col.Descriptors[3], chain.Chain)
+	out <- metric
+}
@@ -1,74 +1,115 @@
 package bitcoind
 
 import (
+	"time"
+
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/prometheus/client_golang/prometheus"
-	"go.uber.org/zap"
 )
 
-// BlockchainDescriptors contains cached descriptor values for collected blockchain metrics
-var BlockchainDescriptors = []*prometheus.Desc{
-	prometheus.NewDesc("bitcoind_blockchain_blocks", "Height of the most-work fully-validated chain", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_headers", "Current number of headers validated", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_difficulty", "Current difficulty metric", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_median_time", "Median time for the current best block", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_verification_progress", "Estimate of verification progress on range [0..1]", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_initial_block_download", "Estimate of whether this node is in Initial Block Download mode", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_size_on_disk", "Estimated size of the block and undo files on disk", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_blockchain_prune_height", "Height of the last block pruned, plus one", []string{"chain"}, prometheus.Labels{}),
+// NewBlockchainDescriptors creates descriptors for collected blockchain metrics
+func NewBlockchainDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_blocks"), "Height of the most-work fully-validated chain", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_headers"), "Current number of headers validated", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_difficulty"), "Current difficulty metric", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_blockchain_median_time", "bitcoind_blockchain_median_time_seconds"), "Median time for the current best block", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_verification_progress"), "Estimate of verification progress on range [0..1]", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_initial_block_download"), "Estimate of whether this node is in Initial Block Download mode", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_blockchain_size_on_disk", "bitcoind_blockchain_size_on_disk_bytes"), "Estimated size of the block and undo files on disk", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_prune_height"), "Height of the last block pruned, plus one", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blockchain_time_drift_seconds"), "Exporter host's clock minus the time of the best block (reference tip) or its median time past (reference median_time). Large positive values indicate a stalled chain, and negative values a clock problem", []string{"chain", "reference"}, opts.ConstLabels),
+	}
 }
 
 // NewBlockchainCollector creates a new prometheus.Collector for getblockchaininfo properties
-func NewBlockchainCollector(client *rpcclient.Client, logger *zap.Logger) prometheus.Collector {
-	return &BlockchainCollector{client, logger}
+func NewBlockchainCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &BlockchainCollector{client, logger, opts, NewBlockchainDescriptors(opts)}
 }
 
 // BlockchainCollector builds metrics from getblockchaininfo RPC responses
 type BlockchainCollector struct {
 	*rpcclient.Client
-	*zap.Logger
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
 }
 
 // Describe returns the collector's metric descriptor set
 func (col *BlockchainCollector) Describe(out chan<- *prometheus.Desc) {
-	for _, desc := range BlockchainDescriptors {
+	for _, desc := range col.Descriptors {
 		out <- desc
 	}
 }
 
 // Collect calls the getblockchaininfo RPC and builds metrics from its response properties
 func (col *BlockchainCollector) Collect(out chan<- prometheus.Metric) {
-	info, err := col.GetBlockChainInfo()
+	info, err := col.BlockChainInfo(col.Client)
 	if err != nil {
-		col.Error("RPC call getblockchaininfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getblockchaininfo", err)
 		return
 	}
 
-	metric, _ := prometheus.NewConstMetric(BlockchainDescriptors[0], prometheus.CounterValue, float64(info.Blocks), info.Chain)
+	LastCollection.Mark()
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], col.HeightType(), float64(info.Blocks), info.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[1], prometheus.CounterValue, float64(info.Headers), info.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], col.HeightType(), float64(info.Headers), info.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[2], prometheus.GaugeValue, float64(info.Difficulty), info.Chain)
+	// Elements blocks are signed by a federation, and have no proof of work difficulty
+	if !col.Elements() {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(info.Difficulty), info.Chain)
+		out <- metric
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(info.MedianTime), info.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[3], prometheus.GaugeValue, float64(info.MedianTime), info.Chain)
+	now := time.Now()
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[8], prometheus.GaugeValue, now.Sub(time.Unix(info.MedianTime, 0)).Seconds(), info.Chain, "median_time")
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[4], prometheus.GaugeValue, info.VerificationProgress, info.Chain)
+	// getblockchaininfo reports the best block's time from v23.0.0. Older nodes' block times are read from the
+	// best block's header, if the node serves getblockheader
+	tip := info.Time
+	if tip == 0 && col.Methods.Supports("getblockheader") {
+		header, err := col.BlockHeader(col.Client, info.BestBlockHash)
+		if err != nil {
+			LogRPCError(col.Logger, "getblockheader", err)
+		} else {
+			tip = header.Time
+		}
+	}
+
+	if tip > 0 {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[8], prometheus.GaugeValue, now.Sub(time.Unix(tip, 0)).Seconds(), info.Chain, "tip")
+		out <- metric
+	}
+
+	// btcd does not report verification progress, IBD state, disk usage or pruning
+	if !col.Core() {
+		return
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, info.VerificationProgress, info.Chain)
 	out <- metric
 
 	if info.InitialBlockDownload {
-		metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[5], prometheus.UntypedValue, 1, info.Chain)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[5], col.BoolType(), 1, info.Chain)
 	} else {
-		metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[5], prometheus.UntypedValue, 0, info.Chain)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[5], col.BoolType(), 0, info.Chain)
 	}
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[6], prometheus.GaugeValue, float64(info.SizeOnDisk), info.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[6], prometheus.GaugeValue, float64(info.SizeOnDisk), info.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(BlockchainDescriptors[7], prometheus.GaugeValue, float64(info.PruneHeight), info.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[7], prometheus.GaugeValue, float64(info.PruneHeight), info.Chain)
 	out <- metric
 }
@@ -0,0 +1,51 @@
+package bitcoind_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBlockchainCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockchainCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_blockchain_blocks", chain, bitcoindtest.Height)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blockchain_headers", chain, bitcoindtest.Height+1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blockchain_median_time", chain, 1690000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_initial_block_download", chain, 0)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blockchain_size_on_disk", chain, 550000000000)
+}
+
+func TestBlockchainCollectorTimeDrift(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockchainCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	for _, reference := range []string{"tip", "median_time"} {
+		if _, has := bitcoindtest.Find(families, "bitcoind_blockchain_time_drift_seconds", prometheus.Labels{"reference": reference}); !has {
+			t.Errorf("no %s time drift from getblockchaininfo", reference)
+		}
+	}
+
+	if calls := server.Calls("getblockheader"); calls != 0 {
+		t.Errorf("getblockheader called %d times, when getblockchaininfo reports the tip time", calls)
+	}
+}
+
+func TestBlockchainCollectorREST(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	rest := bitcoind.NewRESTClient(server.URL, time.Second)
+
+	col := bitcoind.NewBlockchainCollector(nil, bitcoindtest.Logger(t), bitcoind.WithREST(rest))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_blockchain_blocks", prometheus.Labels{"chain": bitcoindtest.Chain}, bitcoindtest.Height)
+}
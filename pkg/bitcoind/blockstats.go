@@ -0,0 +1,468 @@
+package bitcoind
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// MaxBlockWeight is the consensus limit on block weight
+const MaxBlockWeight = 4000000
+
+// BlockStatsQuantiles are exported for block weight utilization over the window of recent blocks
+var BlockStatsQuantiles = []float64{0.5, 0.9, 0.99}
+
+// BlockStatsBatch is the maximum number of blocks requested by each collection. The window is filled over several
+// collections when the exporter starts, or after the node has been unreachable, so that scrapes do not time out
+var BlockStatsBatch = 16
+
+// BlockStatsTaprootBatch replaces BlockStatsBatch when taproot spends are counted, as each block is also requested
+// with the prevouts of its inputs, which are several MB
+var BlockStatsTaprootBatch = 4
+
+// BlockStatsFields are requested from getblockstats, which skips computing other stats
+var BlockStatsFields = []string{"blockhash", "height", "total_size", "total_weight", "txs", "swtxs", "totalfee", "subsidy"}
+
+// NewBlockStatsDescriptors creates descriptors for collected block stats metrics
+func NewBlockStatsDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_window"), "Number of recent blocks from which block stats metrics are built", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_weight_utilization_avg"), "Average ratio of transaction weight to the maximum block weight over recent blocks. The coinbase transaction and block header are not counted", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_weight_utilization"), "Quantiles of the ratio of transaction weight to the maximum block weight over recent blocks. The coinbase transaction and block header are not counted", []string{"chain", "quantile"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_segwit_spend_ratio"), "Ratio of transactions with witness data, which spend segwit outputs, to all transactions in recent blocks, excluding coinbase transactions", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_witness_size_ratio"), "Ratio of witness bytes to the total size of transactions in recent blocks, excluding coinbase transactions", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_blocks_taproot_spend_ratio"), "Ratio of transactions spending one or more taproot outputs to all transactions in recent blocks, excluding coinbase transactions", []string{"chain"}, opts.ConstLabels),
+	}
+}
+
+// NewBlockStatsCollector creates a new prometheus.Collector for getblockstats results over a window of recent blocks.
+// If taproot is set, taproot spends are counted from blocks with prevouts, from getblock verbosity 3
+func NewBlockStatsCollector(client *rpcclient.Client, logger Logger, window int64, taproot bool, options ...Option) *BlockStatsCollector {
+	opts := NewOptions(options...)
+
+	return &BlockStatsCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Window:      window,
+		Taproot:     taproot,
+		Descriptors: NewBlockStatsDescriptors(opts),
+
+		Fees: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_blocks_fees_total"), Help: "Total fees in " + opts.AmountUnit() + " paid by transactions in blocks connected to the chain tip since the exporter started", ConstLabels: opts.FeeLabels(),
+		}, []string{"chain"}),
+		Subsidy: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_blocks_subsidy_total"), Help: "Total block subsidy in " + opts.AmountUnit() + " of blocks connected to the chain tip since the exporter started", ConstLabels: opts.FeeLabels(),
+		}, []string{"chain"}),
+		Blocks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_blocks_counted_total"), Help: "Number of blocks whose fees and subsidy have been added to bitcoind_blocks_fees_total and bitcoind_blocks_subsidy_total", ConstLabels: opts.ConstLabels,
+		}, []string{"chain"}),
+
+		blocks:  map[int64]BlockStats{},
+		counted: -1,
+	}
+}
+
+// BlockStats decodes the BlockStatsFields of a getblockstats response
+type BlockStats struct {
+	Hash        string `json:"blockhash"`
+	Height      int64  `json:"height"`
+	TotalSize   int64  `json:"total_size"`
+	TotalWeight int64  `json:"total_weight"`
+	Txs         int64  `json:"txs"`
+	SegWitTxs   int64  `json:"swtxs"`
+	TotalFee    int64  `json:"totalfee"`
+	Subsidy     int64  `json:"subsidy"`
+
+	// TaprootTxs is counted from getblock, and is not a getblockstats field
+	TaprootTxs int64 `json:"-"`
+}
+
+// Utilization returns the ratio of the block's transaction weight to MaxBlockWeight
+func (stats BlockStats) Utilization() float64 {
+	return float64(stats.TotalWeight) / MaxBlockWeight
+}
+
+// WitnessSize returns the number of witness bytes in the block's transactions, excluding the coinbase. Weight counts
+// non-witness bytes four times and witness bytes once, so the non-witness size is a third of weight minus size
+func (stats BlockStats) WitnessSize() int64 {
+	return stats.TotalSize - (stats.TotalWeight-stats.TotalSize)/3
+}
+
+// GetBlockPrevoutsResult decodes the output types spent by transaction inputs from the getblock verbosity 3 response
+type GetBlockPrevoutsResult struct {
+	Tx []struct {
+		Vin []struct {
+			Prevout *struct {
+				ScriptPubKey struct {
+					Type string `json:"type"`
+				} `json:"scriptPubKey"`
+			} `json:"prevout"`
+		} `json:"vin"`
+	} `json:"tx"`
+}
+
+// TaprootTxs counts transactions that spend one or more taproot outputs. Coinbase inputs have no prevout
+func (block GetBlockPrevoutsResult) TaprootTxs() int64 {
+	var count int64
+
+	for _, tx := range block.Tx {
+		for _, vin := range tx.Vin {
+			if vin.Prevout != nil && vin.Prevout.ScriptPubKey.Type == "witness_v1_taproot" {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+// BlockStatsCollector builds metrics from getblockstats RPC responses for the last Window blocks. Stats are
+// cached by height, so that each block is requested once unless it is replaced by a reorg. Fees and subsidy
+// are added to counters once for each height above the tip of the first collection, so blocks replaced by a
+// reorg are not counted again
+type BlockStatsCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Window      int64
+	Taproot     bool
+	Descriptors []*prometheus.Desc
+
+	Fees    *prometheus.CounterVec
+	Subsidy *prometheus.CounterVec
+	Blocks  *prometheus.CounterVec
+
+	mu      sync.Mutex
+	tip     string
+	blocks  map[int64]BlockStats
+	counted int64
+}
+
+// Inherit takes over the counters, cached stats and counted height of a previous BlockStatsCollector
+func (col *BlockStatsCollector) Inherit(previous prometheus.Collector) {
+	prev, ok := previous.(*BlockStatsCollector)
+	if !ok {
+		return
+	}
+
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+
+	col.Fees, col.Subsidy, col.Blocks = prev.Fees, prev.Subsidy, prev.Blocks
+	col.tip, col.blocks, col.counted = prev.tip, prev.blocks, prev.counted
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *BlockStatsCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+
+	col.Fees.Describe(out)
+	col.Subsidy.Describe(out)
+	col.Blocks.Describe(out)
+}
+
+// Collect calls the getblockstats RPC for blocks in the window that are not cached, and builds metrics from
+// the window's stats
+func (col *BlockStatsCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	blocks, err := col.update(chain)
+	if err != nil {
+		return
+	}
+
+	LastCollection.Mark()
+
+	// Blocks that were connected before the first collection are in the window, but are not counted. The
+	// counters start from zero
+	if col.counted < 0 {
+		col.counted = int64(chain.Blocks)
+
+		col.Fees.WithLabelValues(chain.Chain)
+		col.Subsidy.WithLabelValues(chain.Chain)
+		col.Blocks.WithLabelValues(chain.Chain)
+	}
+
+	for _, stats := range blocks {
+		if stats.Height <= col.counted {
+			continue
+		}
+
+		col.Fees.WithLabelValues(chain.Chain).Add(col.Amount(float64(stats.TotalFee) / 1e8))
+		col.Subsidy.WithLabelValues(chain.Chain).Add(col.Amount(float64(stats.Subsidy) / 1e8))
+		col.Blocks.WithLabelValues(chain.Chain).Inc()
+
+		col.counted = stats.Height
+	}
+
+	col.Fees.Collect(out)
+	col.Subsidy.Collect(out)
+	col.Blocks.Collect(out)
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(len(blocks)), chain.Chain)
+	out <- metric
+
+	if len(blocks) == 0 {
+		return
+	}
+
+	var total float64
+	utilization := make([]float64, 0, len(blocks))
+
+	for _, stats := range blocks {
+		total += stats.Utilization()
+		utilization = append(utilization, stats.Utilization())
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, total/float64(len(blocks)), chain.Chain)
+	out <- metric
+
+	for _, q := range BlockStatsQuantiles {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, Quantile(utilization, q), chain.Chain, strconv.FormatFloat(q, 'f', -1, 64))
+		out <- metric
+	}
+
+	// Ratios are of totals over the window, so that blocks with more transactions carry more weight
+	var txs, segwit, taproot, size, witness int64
+	for _, stats := range blocks {
+		txs += stats.Txs - 1
+		segwit += stats.SegWitTxs
+		taproot += stats.TaprootTxs
+		size += stats.TotalSize
+		witness += stats.WitnessSize()
+	}
+
+	if txs > 0 {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(segwit)/float64(txs), chain.Chain)
+		out <- metric
+
+		if col.Taproot && col.Supports(Version23) {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[5], prometheus.GaugeValue, float64(taproot)/float64(txs), chain.Chain)
+			out <- metric
+		}
+	}
+
+	if size > 0 {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, float64(witness)/float64(size), chain.Chain)
+		out <- metric
+	}
+}
+
+// update requests stats for blocks in the window that are not cached, and returns the window's stats in height order.
+// Errors are logged. The caller must hold col.mu
+func (col *BlockStatsCollector) update(chain *GetBlockChainInfoResult) ([]BlockStats, error) {
+	from := int64(chain.Blocks) - col.Window + 1
+
+	// Pruned nodes do not have the undo data that getblockstats reads for blocks below the prune height
+	if chain.Pruned && from < int64(chain.PruneHeight) {
+		from = int64(chain.PruneHeight)
+	}
+
+	if from < 0 {
+		from = 0
+	}
+
+	if chain.BestBlockHash != col.tip {
+		err := col.reorg(chain)
+		if err != nil {
+			return nil, err
+		}
+
+		col.tip = chain.BestBlockHash
+	}
+
+	for height := range col.blocks {
+		if height < from || height > int64(chain.Blocks) {
+			delete(col.blocks, height)
+		}
+	}
+
+	// Send every request before waiting for any responses
+	futures := map[int64]chan *rpcclient.Response{}
+	for _, height := range col.missing(from, int64(chain.Blocks)) {
+		futures[height] = col.SendCmd(btcjson.NewGetBlockStatsCmd(btcjson.HashOrHeight{Value: int(height)}, &BlockStatsFields))
+	}
+
+	for height, future := range futures {
+		data, err := col.Receive(future)
+		if err != nil {
+			LogRPCError(col.Logger, "getblockstats", err)
+			return nil, err
+		}
+
+		var stats BlockStats
+		err = col.Decode("getblockstats", data, &stats)
+
+		if err != nil {
+			col.Error("Failed to decode getblockstats response", zap.Error(err))
+			return nil, err
+		}
+
+		col.blocks[height] = stats
+	}
+
+	// getblock reports the outputs spent by inputs from v23.0.0
+	if col.Taproot && col.Supports(Version23) {
+		err := col.countTaproot(futures)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blocks := make([]BlockStats, 0, len(col.blocks))
+	for _, stats := range col.blocks {
+		blocks = append(blocks, stats)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+
+	return blocks, nil
+}
+
+// missing returns up to BlockStatsBatch, or BlockStatsTaprootBatch, heights from from to tip that are not cached. Blocks above the last counted
+// height are returned first in height order, so that they are counted in turn, and then older blocks from the
+// newest. The caller must hold col.mu
+func (col *BlockStatsCollector) missing(from, tip int64) []int64 {
+	batch := BlockStatsBatch
+	if col.Taproot && col.Supports(Version23) {
+		batch = BlockStatsTaprootBatch
+	}
+
+	var heights []int64
+	add := func(height int64) bool {
+		if _, has := col.blocks[height]; !has {
+			heights = append(heights, height)
+		}
+
+		return len(heights) < batch
+	}
+
+	older := tip
+	if col.counted >= 0 {
+		newer := col.counted + 1
+		if newer < from {
+			newer = from
+		}
+
+		for height := newer; height <= tip; height++ {
+			if !add(height) {
+				return heights
+			}
+		}
+
+		older = newer - 1
+	}
+
+	for height := older; height >= from; height-- {
+		if !add(height) {
+			return heights
+		}
+	}
+
+	return heights
+}
+
+// countTaproot requests blocks with prevouts for newly cached stats, and counts their taproot spends
+func (col *BlockStatsCollector) countTaproot(heights map[int64]chan *rpcclient.Response) error {
+	verbosity := 3
+
+	futures := map[int64]chan *rpcclient.Response{}
+	for height := range heights {
+		futures[height] = col.SendCmd(btcjson.NewGetBlockCmd(col.blocks[height].Hash, &verbosity))
+	}
+
+	for height, future := range futures {
+		data, err := col.Receive(future)
+		if err != nil {
+			col.evict(heights)
+			LogRPCError(col.Logger, "getblock", err)
+
+			return err
+		}
+
+		var block GetBlockPrevoutsResult
+		err = col.Decode("getblock", data, &block)
+
+		if err != nil {
+			col.evict(heights)
+			col.Error("Failed to decode getblock response", zap.Error(err))
+
+			return err
+		}
+
+		stats := col.blocks[height]
+		stats.TaprootTxs = block.TaprootTxs()
+		col.blocks[height] = stats
+	}
+
+	return nil
+}
+
+// evict removes stats from the cache, so that they are requested again by the next collection
+func (col *BlockStatsCollector) evict(heights map[int64]chan *rpcclient.Response) {
+	for height := range heights {
+		delete(col.blocks, height)
+	}
+}
+
+// reorg clears the cache if its blocks have been replaced by a reorg. A block cached at the height of the new tip
+// must be the tip, and the highest cached block below it must still be in the active chain, in which case every
+// block below it is as well
+func (col *BlockStatsCollector) reorg(chain *GetBlockChainInfoResult) error {
+	tip := int64(chain.Blocks)
+	if stats, has := col.blocks[tip]; has && stats.Hash != chain.BestBlockHash {
+		col.Debug("Clearing block stats after reorg", zap.Int64("height", tip))
+		col.blocks = map[int64]BlockStats{}
+
+		return nil
+	}
+
+	height := int64(-1)
+	for cached := range col.blocks {
+		if cached < tip && cached > height {
+			height = cached
+		}
+	}
+
+	if height < 0 {
+		return nil
+	}
+
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetBlockHashCmd(height)))
+	if err != nil {
+		LogRPCError(col.Logger, "getblockhash", err)
+		return err
+	}
+
+	var hash string
+	err = col.Decode("getblockhash", data, &hash)
+
+	if err != nil {
+		col.Error("Failed to decode getblockhash response", zap.Error(err))
+		return err
+	}
+
+	if hash != col.blocks[height].Hash {
+		col.Debug("Clearing block stats after reorg", zap.Int64("height", height))
+		col.blocks = map[int64]BlockStats{}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBlockStatsCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockStatsCollector(server.Client(t), bitcoindtest.Logger(t), 4, false)
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_window", chain, 4)
+
+	value, has := bitcoindtest.Value(families, "bitcoind_blocks_weight_utilization_avg", chain)
+	if !has || value <= 0 || value > 1 {
+		t.Errorf("bitcoind_blocks_weight_utilization_avg = %v, expected a ratio", value)
+	}
+
+	// Block weights in the window are 3990000, 3000000, 3010000 and 3020000
+	median, _ := bitcoindtest.Value(families, "bitcoind_blocks_weight_utilization", prometheus.Labels{"quantile": "0.5"})
+	if median < 0.7537 || median > 0.7538 {
+		t.Errorf("median weight utilization = %v, expected 0.75375", median)
+	}
+}
+
+func TestBlockStatsCollectorWindowFill(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockStatsCollector(server.Client(t), bitcoindtest.Logger(t), int64(bitcoind.BlockStatsBatch)+4, false)
+
+	families := bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_window", prometheus.Labels{"chain": bitcoindtest.Chain}, float64(bitcoind.BlockStatsBatch))
+
+	families = bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_window", prometheus.Labels{"chain": bitcoindtest.Chain}, float64(bitcoind.BlockStatsBatch)+4)
+}
+
+func TestBlockStatsCollectorAdoption(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockStatsCollector(server.Client(t), bitcoindtest.Logger(t), 4, true)
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	for _, name := range []string{"bitcoind_blocks_segwit_spend_ratio", "bitcoind_blocks_witness_size_ratio", "bitcoind_blocks_taproot_spend_ratio"} {
+		value, has := bitcoindtest.Value(families, name, chain)
+		if !has {
+			t.Errorf("no metric %s", name)
+			continue
+		}
+
+		if value <= 0 || value > 1 {
+			t.Errorf("%s = %v, expected a ratio", name, value)
+		}
+	}
+
+	// Taproot spends are only counted when enabled
+	col = bitcoind.NewBlockStatsCollector(server.Client(t), bitcoindtest.Logger(t), 4, false)
+	families = bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_blocks_taproot_spend_ratio", nil)
+}
+
+func TestBlockStatsCollectorCounted(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	info := bitcoindtest.Fixtures(bitcoind.Version24)["getblockchaininfo"].(bitcoindtest.Object)
+	info["blocks"] = bitcoindtest.Height - 1
+	info["bestblockhash"] = bitcoindtest.BlockHashAt(bitcoindtest.Height - 1)
+	server.Set("getblockchaininfo", info)
+
+	col := bitcoind.NewBlockStatsCollector(server.Client(t), bitcoindtest.Logger(t), 4, false, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	// Blocks in the window when the exporter starts are not counted
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_counted_total", chain, 0)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_fees_total", chain, 0)
+
+	server.Set("getblockchaininfo", bitcoindtest.Fixtures(bitcoind.Version24)["getblockchaininfo"])
+	families = bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_counted_total", chain, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_fees_total", chain, 20000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_blocks_subsidy_total", chain, 625000000)
+}
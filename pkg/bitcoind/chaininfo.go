@@ -0,0 +1,53 @@
+package bitcoind
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// CachedChainInfo is a ChainInfoProvider that shares a getblockchaininfo result between collectors for up
+// to TTL, so that collectors gathered together in one scrape call getblockchaininfo once
+type CachedChainInfo struct {
+	*rpcclient.Client
+	Options
+
+	TTL time.Duration
+
+	mu      sync.Mutex
+	info    *GetBlockChainInfoResult
+	fetched time.Time
+}
+
+// NewCachedChainInfo creates a CachedChainInfo provider calling getblockchaininfo through client, with the
+// timeout, REST client and flavor of the collectors' options
+func NewCachedChainInfo(client *rpcclient.Client, ttl time.Duration, options ...Option) *CachedChainInfo {
+	opts := NewOptions(options...)
+
+	// The provider is the source of the shared result, and must not read it from another provider
+	opts.ChainInfo = nil
+
+	return &CachedChainInfo{Client: client, Options: opts, TTL: ttl}
+}
+
+// BlockChainInfo returns the cached getblockchaininfo result, or calls getblockchaininfo if it has expired.
+// Errors are not cached
+func (cache *CachedChainInfo) BlockChainInfo() (*GetBlockChainInfoResult, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.info != nil && time.Since(cache.fetched) < cache.TTL {
+		return cache.info, nil
+	}
+
+	info, err := cache.Options.BlockChainInfo(cache.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.info = info
+	cache.fetched = time.Now()
+
+	return info, nil
+}
@@ -0,0 +1,49 @@
+package bitcoind_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCachedChainInfo(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	client := server.Client(t)
+	logger := bitcoindtest.Logger(t)
+
+	cache := bitcoind.NewCachedChainInfo(client, time.Minute)
+	families := bitcoindtest.Gather(t,
+		bitcoind.NewMempoolCollector(client, logger, bitcoind.WithChainInfo(cache)),
+		bitcoind.NewChainTipsCollector(client, logger, bitcoind.WithChainInfo(cache)),
+	)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_size", prometheus.Labels{"chain": bitcoindtest.Chain}, 5000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"chain": bitcoindtest.Chain, "status": "active"}, 1)
+
+	if calls := server.Calls("getblockchaininfo"); calls != 1 {
+		t.Errorf("getblockchaininfo called %d times, expected 1", calls)
+	}
+}
+
+func TestCachedChainInfoOptions(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	info := bitcoindtest.Fixtures(bitcoind.Version24)["getblockchaininfo"].(bitcoindtest.Object)
+	info["chain"] = "mainnet"
+	server.Set("getblockchaininfo", info)
+
+	// btcd's network names are mapped to Bitcoin Core's chain names by the cache's flavor
+	cache := bitcoind.NewCachedChainInfo(server.Client(t), time.Minute, bitcoind.WithOptions(bitcoind.Options{Flavor: bitcoind.FlavorBtcd}))
+
+	chain, err := cache.BlockChainInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chain.Chain != bitcoindtest.Chain {
+		t.Errorf("chain = %q, expected %q", chain.Chain, bitcoindtest.Chain)
+	}
+}
@@ -0,0 +1,140 @@
+package bitcoind
+
+// getchainstates
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NewChainstatesDescriptors creates descriptors for collected chainstate metrics
+func NewChainstatesDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstates"), "Number of chainstates on the node. There are two while an assumeutxo snapshot is being validated", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_blocks"), "Height of the chainstate's tip", []string{"chain", "chainstate"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_verification_progress"), "Estimate of verification progress [0..1] for the chainstate", []string{"chain", "chainstate"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_validated"), "Whether the chainstate is fully validated or not. A snapshot chainstate is not validated until background validation reaches its base block", []string{"chain", "chainstate"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_coins_db_cache_bytes"), "Size of the coinsdb cache for the chainstate", []string{"chain", "chainstate"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_coins_tip_cache_bytes"), "Size of the coinstip cache for the chainstate", []string{"chain", "chainstate"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_chainstate_snapshot_info"), "Base block of the chainstate's assumeutxo snapshot", []string{"chain", "chainstate", "snapshot_blockhash"}, opts.ConstLabels),
+	}
+}
+
+// NewChainstatesCollector creates a new prometheus.Collector for getchainstates properties
+func NewChainstatesCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &ChainstatesCollector{client, logger, opts, NewChainstatesDescriptors(opts)}
+}
+
+// ChainstatesCollector builds metrics from getchainstates RPC responses
+type ChainstatesCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ChainstatesCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// GetChainstatesCmd calls the getchainstates RPC
+type GetChainstatesCmd struct{}
+
+func init() {
+	btcjson.MustRegisterCmd("getchainstates", (*GetChainstatesCmd)(nil), btcjson.UsageFlag(0))
+}
+
+// GetChainstatesResult decodes the getchainstates (v26.0.0) RPC response
+type GetChainstatesResult struct {
+	Headers     int64 `json:"headers"`
+	Chainstates []struct {
+		Blocks               int64   `json:"blocks"`
+		BestBlockHash        string  `json:"bestblockhash"`
+		VerificationProgress float64 `json:"verificationprogress"`
+		SnapshotBlockHash    string  `json:"snapshot_blockhash"`
+		CoinsDBCacheBytes    int64   `json:"coins_db_cache_bytes"`
+		CoinsTipCacheBytes   int64   `json:"coins_tip_cache_bytes"`
+		Validated            bool    `json:"validated"`
+	} `json:"chainstates"`
+}
+
+// Collect calls the getchainstates RPC and builds metrics from its response properties
+func (col *ChainstatesCollector) Collect(out chan<- prometheus.Metric) {
+	if !col.Supports(Version26) {
+		return
+	}
+
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	data, err := col.Receive(col.SendCmd(&GetChainstatesCmd{}))
+	if err != nil {
+		// getchainstates was added in v26.0.0, which is only known in advance if the node's version is set
+		var rpcErr *btcjson.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code {
+			col.Debug("RPC call getchainstates is not supported by the node", zap.Error(err))
+			return
+		}
+
+		LogRPCError(col.Logger, "getchainstates", err)
+		return
+	}
+
+	var info GetChainstatesResult
+	err = col.Decode("getchainstates", data, &info)
+
+	if err != nil {
+		col.Error("Failed to decode getchainstates response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(len(info.Chainstates)), chain.Chain)
+	out <- metric
+
+	for _, state := range info.Chainstates {
+		// The chainstate created from an assumeutxo snapshot reports its base block
+		name := "normal"
+		if state.SnapshotBlockHash != "" {
+			name = "snapshot"
+		}
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[1], col.HeightType(), float64(state.Blocks), chain.Chain, name)
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, state.VerificationProgress, chain.Chain, name)
+		out <- metric
+
+		if state.Validated {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, 1, chain.Chain, name)
+		} else {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, 0, chain.Chain, name)
+		}
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, float64(state.CoinsDBCacheBytes), chain.Chain, name)
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[5], prometheus.GaugeValue, float64(state.CoinsTipCacheBytes), chain.Chain, name)
+		out <- metric
+
+		if state.SnapshotBlockHash != "" {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[6], prometheus.GaugeValue, 1, chain.Chain, name, state.SnapshotBlockHash)
+			out <- metric
+		}
+	}
+}
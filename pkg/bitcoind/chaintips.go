@@ -0,0 +1,146 @@
+package bitcoind
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ChainTipStatuses are the statuses of chain tips reported by getchaintips. Tips with any status other than
+// active are the ends of stale branches
+var ChainTipStatuses = []string{"active", "valid-fork", "valid-headers", "headers-only", "invalid"}
+
+// ChainTip is an element of the getchaintips RPC response
+type ChainTip struct {
+	Height    int64  `json:"height"`
+	Hash      string `json:"hash"`
+	BranchLen int64  `json:"branchlen"`
+	Status    string `json:"status"`
+}
+
+// NewChainTipsDescriptors creates descriptors for collected chain tip metrics
+func NewChainTipsDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_chain_tips"), "Number of chain tips known to the node by status. Tips other than the active tip end stale branches, which may have been seen before the node started", []string{"chain", "status"}, opts.ConstLabels),
+	}
+}
+
+// NewChainTipsCollector creates a new prometheus.Collector for getchaintips results
+func NewChainTipsCollector(client *rpcclient.Client, logger Logger, options ...Option) *ChainTipsCollector {
+	opts := NewOptions(options...)
+
+	return &ChainTipsCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Descriptors: NewChainTipsDescriptors(opts),
+
+		Stale: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_stale_blocks_observed_total"), Help: "Number of stale chain tips observed since the exporter started, from blocks that lost a race for the active chain or that were disconnected by a reorg. A stale branch that grows by several blocks between collections is counted once", ConstLabels: opts.ConstLabels,
+		}, []string{"chain", "status"}),
+	}
+}
+
+// ChainTipsCollector builds metrics from getchaintips RPC responses. Stale tips are remembered between
+// collections so that each is counted once. Tips reported by the first collection are not counted, as they
+// may be from any time since the node started
+type ChainTipsCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+
+	Stale *prometheus.CounterVec
+
+	mu    sync.Mutex
+	stale map[string]struct{}
+}
+
+// Inherit takes over the stale tip counter and the stale tips seen by a previous ChainTipsCollector
+func (col *ChainTipsCollector) Inherit(previous prometheus.Collector) {
+	prev, ok := previous.(*ChainTipsCollector)
+	if !ok {
+		return
+	}
+
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+
+	col.Stale, col.stale = prev.Stale, prev.stale
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ChainTipsCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+
+	col.Stale.Describe(out)
+}
+
+// Collect calls the getchaintips RPC, counts newly observed stale tips, and builds metrics from the tips
+func (col *ChainTipsCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetChainTipsCmd()))
+	if err != nil {
+		LogRPCError(col.Logger, "getchaintips", err)
+		return
+	}
+
+	var tips []ChainTip
+	err = col.Decode("getchaintips", data, &tips)
+
+	if err != nil {
+		col.Error("Failed to decode getchaintips response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	var active int64
+	for _, tip := range tips {
+		if tip.Status == "active" {
+			active = tip.Height
+		}
+	}
+
+	// The active tip becomes stale when a reorg replaces it, so only stale tips are remembered. Tips that the
+	// node no longer reports are forgotten. Headers that extend the active tip, e.g. during initial block
+	// download or before their blocks are connected, are not stale
+	first := col.stale == nil
+	stale := make(map[string]struct{}, len(tips))
+	statuses := map[string]int{}
+
+	for _, tip := range tips {
+		statuses[tip.Status]++
+		if tip.Status == "active" || tip.Height-tip.BranchLen == active {
+			continue
+		}
+
+		stale[tip.Hash] = struct{}{}
+		if _, seen := col.stale[tip.Hash]; !seen && !first {
+			col.Debug("Observed stale chain tip", zap.String("hash", tip.Hash), zap.Int64("height", tip.Height), zap.Int64("branchlen", tip.BranchLen), zap.String("status", tip.Status))
+			col.Stale.WithLabelValues(chain.Chain, tip.Status).Inc()
+		}
+	}
+
+	col.stale = stale
+	col.Stale.Collect(out)
+
+	for _, status := range ChainTipStatuses {
+		metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(statuses[status]), chain.Chain, status)
+		out <- metric
+	}
+}
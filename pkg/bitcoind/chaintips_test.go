@@ -0,0 +1,61 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestChainTipsCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewChainTipsCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"status": "active"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"status": "valid-fork"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"status": "invalid"}, 0)
+
+	// Tips that are stale when the exporter starts are not counted as observed
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_stale_blocks_observed_total", nil)
+
+	tips := bitcoindtest.Fixtures(bitcoind.Version24)["getchaintips"].([]bitcoindtest.Object)
+	tips = append(tips, bitcoindtest.Object{"height": bitcoindtest.Height, "hash": bitcoindtest.BlockHashAt(1), "branchlen": 1, "status": "valid-headers"})
+	server.Set("getchaintips", tips)
+
+	families = bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertValue(t, families, "bitcoind_stale_blocks_observed_total", prometheus.Labels{"status": "valid-headers"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"status": "valid-headers"}, 1)
+}
+
+func TestChainTipsCollectorHeadersExtension(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewChainTipsCollector(server.Client(t), bitcoindtest.Logger(t))
+	bitcoindtest.Gather(t, col)
+
+	// Headers received ahead of their blocks extend the active chain, and are not stale
+	tips := bitcoindtest.Fixtures(bitcoind.Version24)["getchaintips"].([]bitcoindtest.Object)
+	tips = append(tips,
+		bitcoindtest.Object{"height": bitcoindtest.Height + 1, "hash": bitcoindtest.BlockHashAt(bitcoindtest.Height + 1), "branchlen": 1, "status": "headers-only"},
+		bitcoindtest.Object{"height": bitcoindtest.Height + 2, "hash": bitcoindtest.BlockHashAt(bitcoindtest.Height + 2), "branchlen": 2, "status": "valid-headers"},
+	)
+	server.Set("getchaintips", tips)
+
+	families := bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_stale_blocks_observed_total", nil)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chain_tips", prometheus.Labels{"status": "headers-only"}, 1)
+}
+
+func TestChainstatesCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version26)
+
+	col := bitcoind.NewChainstatesCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithVersion(bitcoind.Version26))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_chainstates", prometheus.Labels{"chain": bitcoindtest.Chain}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chainstate_blocks", prometheus.Labels{"chain": bitcoindtest.Chain}, bitcoindtest.Height)
+	bitcoindtest.AssertValue(t, families, "bitcoind_chainstate_validated", prometheus.Labels{"chain": bitcoindtest.Chain}, 1)
+}
@@ -0,0 +1,160 @@
+package bitcoind
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// Check states, in the order of Nagios plugin exit codes
+const (
+	CheckOK = iota
+	CheckWarning
+	CheckCritical
+	CheckUnknown
+)
+
+// CheckStates names check states for summary lines
+var CheckStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Threshold holds warning and critical levels for a check. A zero level is disabled
+type Threshold struct {
+	Warning  float64
+	Critical float64
+
+	// Below checks for values under the levels instead of over them
+	Below bool
+}
+
+// State evaluates a value against the threshold's levels
+func (threshold Threshold) State(value float64) int {
+	exceeds := func(level float64) bool {
+		if level == 0 {
+			return false
+		}
+
+		if threshold.Below {
+			return value < level
+		}
+
+		return value > level
+	}
+
+	if exceeds(threshold.Critical) {
+		return CheckCritical
+	}
+
+	if exceeds(threshold.Warning) {
+		return CheckWarning
+	}
+
+	return CheckOK
+}
+
+// perfdata formats a value and its levels as Nagios performance data
+func (threshold Threshold) perfdata(label string, value float64, unit string) string {
+	level := func(level float64) string {
+		if level == 0 {
+			return ""
+		}
+
+		return strconv.FormatFloat(level, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%s=%s%s;%s;%s", label, strconv.FormatFloat(value, 'f', -1, 64), unit, level(threshold.Warning), level(threshold.Critical))
+}
+
+// CheckThresholds configures the checks performed by Check
+type CheckThresholds struct {
+	// BlockAge is the number of seconds since the best block's timestamp
+	BlockAge Threshold
+
+	// Peers is the number of connected peers, which is checked with Below
+	Peers Threshold
+
+	// HeadersLag is the number of validated headers beyond the best block
+	HeadersLag Threshold
+
+	// MempoolMinFee is the mempool's minimum fee rate in the configured fee unit
+	MempoolMinFee Threshold
+}
+
+// Check evaluates the node's state against thresholds, and returns the worst state with a Nagios plugin
+// summary line. RPC failures are reported as CheckUnknown
+func Check(client *rpcclient.Client, opts Options, thresholds CheckThresholds) (int, string) {
+	state := CheckOK
+	var problems, perfdata []string
+
+	check := func(name string, threshold Threshold, value float64, unit, display string) {
+		result := threshold.State(value)
+		if result > state {
+			state = result
+		}
+
+		if result != CheckOK {
+			problems = append(problems, fmt.Sprintf("%s %s is %s", name, display, strings.ToLower(CheckStates[result])))
+		}
+
+		perfdata = append(perfdata, threshold.perfdata(strings.ReplaceAll(name, " ", "_"), value, unit))
+	}
+
+	unknown := func(err error) (int, string) {
+		return CheckUnknown, "BITCOIND UNKNOWN - " + err.Error()
+	}
+
+	chain, err := opts.BlockChainInfo(client)
+	if err != nil {
+		return unknown(err)
+	}
+
+	header, err := opts.BlockHeader(client, chain.BestBlockHash)
+	if err != nil {
+		return unknown(err)
+	}
+
+	// The REST interface does not report connections, so peers are only checked over RPC
+	peers := int64(-1)
+	if opts.REST == nil {
+		peers, err = client.GetConnectionCount()
+		if err != nil {
+			return unknown(err)
+		}
+	}
+
+	data, err := opts.MempoolInfo(client)
+	if err != nil {
+		return unknown(err)
+	}
+
+	var mempool GetMempoolInfoResult
+	err = opts.Decode("getmempoolinfo", data, &mempool)
+
+	if err != nil {
+		return unknown(err)
+	}
+
+	age := time.Since(time.Unix(header.Time, 0)).Truncate(time.Second)
+	lag := chain.Headers - chain.Blocks
+	fee := opts.FeeRate(mempool.MinFee)
+
+	check("block age", thresholds.BlockAge, age.Seconds(), "s", age.String())
+	if peers >= 0 {
+		check("peers", thresholds.Peers, float64(peers), "", strconv.FormatInt(peers, 10))
+	}
+	check("headers lag", thresholds.HeadersLag, float64(lag), "", strconv.FormatInt(int64(lag), 10))
+	check("mempool min fee", thresholds.MempoolMinFee, fee, "", strconv.FormatFloat(fee, 'f', -1, 64)+" "+opts.FeeRateUnit())
+
+	summary := fmt.Sprintf("%s chain at height %d", chain.Chain, chain.Blocks)
+	if peers >= 0 {
+		summary += fmt.Sprintf(" with %d peers", peers)
+	}
+
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ")
+	}
+
+	return state, fmt.Sprintf("BITCOIND %s - %s | %s", CheckStates[state], summary, strings.Join(perfdata, " "))
+}
@@ -0,0 +1,67 @@
+package bitcoind
+
+import (
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NewConnectionsDescriptors creates descriptors for collected connection count metrics
+func NewConnectionsDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_connections"), "Current number of connected peers, from getconnectioncount", []string{"chain"}, opts.ConstLabels),
+	}
+}
+
+// NewConnectionsCollector creates a new prometheus.Collector for getconnectioncount results
+func NewConnectionsCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &ConnectionsCollector{client, logger, opts, NewConnectionsDescriptors(opts)}
+}
+
+// ConnectionsCollector builds metrics from getconnectioncount RPC responses. It is much cheaper than the
+// PeersCollector, which decodes every peer's details, for scraping the number of peers at a high frequency
+type ConnectionsCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ConnectionsCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getconnectioncount RPC and builds a metric from its result
+func (col *ConnectionsCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetConnectionCountCmd()))
+	if err != nil {
+		LogRPCError(col.Logger, "getconnectioncount", err)
+		return
+	}
+
+	var count int64
+	err = col.Decode("getconnectioncount", data, &count)
+
+	if err != nil {
+		col.Error("Failed to decode getconnectioncount response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(count), chain.Chain)
+	out <- metric
+}
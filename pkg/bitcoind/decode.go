@@ -0,0 +1,97 @@
+package bitcoind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DecodeErrors counts RPC responses that collectors could not decode, by method and reason. Responses are
+// counted with reason type when they do not match the result's JSON shape, and with reason validation when
+// StrictDecoding rejects their values
+var DecodeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "bitcoind_exporter_decode_errors_total",
+	Help: "Number of RPC responses that could not be decoded by method and reason: type for responses that do not match the expected JSON shape, and validation for values rejected by --strict-decoding",
+}, []string{"method", "reason"})
+
+// Validator is implemented by RPC results that check their decoded values when StrictDecoding is enabled
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError reports an RPC result value that failed validation
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (err *ValidationError) Error() string {
+	return "invalid " + err.Field + ": " + err.Reason
+}
+
+// ErrNullResult is returned by strict decoding for null responses, which would otherwise decode to zero values
+var ErrNullResult = errors.New("result is null")
+
+// DecodeResult decodes an RPC response into result. In strict mode, null responses and results whose
+// Validate method fails are rejected with a *ValidationError or ErrNullResult
+func DecodeResult(data []byte, result interface{}, strict bool) error {
+	err := json.Unmarshal(data, result)
+	if err != nil || !strict {
+		return err
+	}
+
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return ErrNullResult
+	}
+
+	if validator, ok := result.(Validator); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
+
+// Decode decodes an RPC method's response into result, with StrictDecoding if enabled, and counts failures
+func (opts Options) Decode(method string, data []byte, result interface{}) error {
+	err := DecodeResult(data, result, opts.StrictDecoding)
+
+	var validationErr *ValidationError
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNullResult), errors.As(err, &validationErr):
+		DecodeErrors.WithLabelValues(method, "validation").Inc()
+	default:
+		DecodeErrors.WithLabelValues(method, "type").Inc()
+	}
+
+	return err
+}
+
+// WithStrictDecoding rejects RPC responses that fail validation instead of exporting their zero values
+func WithStrictDecoding(strict bool) Option {
+	return func(opts *Options) {
+		opts.StrictDecoding = strict
+	}
+}
+
+// validateCount rejects negative counts and sizes
+func validateCount(field string, value int64) error {
+	if value < 0 {
+		return &ValidationError{field, fmt.Sprintf("negative value %d", value)}
+	}
+
+	return nil
+}
+
+// validateAmount rejects negative, infinite and NaN amounts, fee rates and durations
+func validateAmount(field string, value float64) error {
+	if value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return &ValidationError{field, fmt.Sprintf("invalid value %v", value)}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+)
+
+// Seed corpora are responses from Bitcoin Core nodes, run with e.g. go test -fuzz FuzzGetPeerInfo ./pkg/bitcoind
+
+func FuzzGetPeerInfo(f *testing.F) {
+	// v24.0.1
+	f.Add([]byte(`[{"id":0,"addr":"203.0.113.7:8333","addrbind":"192.0.2.1:50102","addrlocal":"198.51.100.20:50102","network":"ipv4","services":"0000000000000409","servicesnames":["NETWORK","WITNESS","NETWORK_LIMITED"],"relaytxes":true,"lastsend":1690113602,"lastrecv":1690113602,"last_transaction":1690113590,"last_block":1690112916,"bytessent":1493541,"bytesrecv":27911402,"conntime":1690019331,"timeoffset":0,"pingtime":0.032419,"minping":0.030113,"version":70016,"subver":"/Satoshi:24.0.1/","inbound":false,"bip152_hb_to":false,"bip152_hb_from":false,"startingheight":800123,"presynced_headers":-1,"synced_headers":800281,"synced_blocks":800281,"inflight":[],"addr_relay_enabled":true,"addr_processed":4072,"addr_rate_limited":0,"permissions":[],"minfeefilter":0.00001000,"bytessent_per_msg":{"addrv2":4311,"feefilter":32,"getdata":91052,"headers":25062,"inv":1262812,"ping":7680,"pong":7680,"sendaddrv2":24,"sendcmpct":66,"sendheaders":24,"verack":24,"version":127,"wtxidrelay":24},"bytesrecv_per_msg":{"addrv2":168013,"blocktxn":1003215,"cmpctblock":1874311,"feefilter":32,"headers":4452,"inv":2061377,"ping":7680,"pong":7680,"sendaddrv2":24,"sendcmpct":66,"sendheaders":24,"tx":22782598,"verack":24,"version":126,"wtxidrelay":24},"connection_type":"outbound-full-relay"}]`))
+	// v0.21.2, with a mapped AS
+	f.Add([]byte(`[{"id":12,"addr":"[2001:db8::5]:8333","network":"ipv6","mapped_as":64496,"services":"000000000000040d","servicesnames":["NETWORK","BLOOM","WITNESS","NETWORK_LIMITED"],"relaytxes":true,"lastsend":1650000010,"lastrecv":1650000011,"last_transaction":0,"last_block":0,"bytessent":2048,"bytesrecv":4096,"conntime":1649990000,"timeoffset":-1,"pingtime":0.11,"minping":0.09,"version":70016,"subver":"/Satoshi:0.21.2/","inbound":true,"addnode":false,"startingheight":731000,"banscore":0,"synced_headers":731010,"synced_blocks":731010,"inflight":[],"whitelisted":false,"permissions":["noban"],"minfeefilter":0.00001000,"bytessent_per_msg":{"ping":32},"bytesrecv_per_msg":{"pong":32},"connection_type":"inbound"}]`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var peers bitcoind.GetPeerInfoResults
+		if bitcoind.DecodeResult(data, &peers, true) != nil {
+			return
+		}
+
+		for _, peer := range peers {
+			peer.ASN()
+			peer.Connection()
+			peer.PermissionList()
+			peer.FromBtcd()
+		}
+	})
+}
+
+func FuzzGetMempoolInfo(f *testing.F) {
+	// v24.0.1
+	f.Add([]byte(`{"loaded":true,"size":31342,"bytes":14876433,"usage":84127936,"total_fee":1.81743220,"maxmempool":300000000,"mempoolminfee":0.00001000,"minrelaytxfee":0.00001000,"incrementalrelayfee":0.00001000,"unbroadcastcount":0,"fullrbf":false}`))
+	// v0.19.1
+	f.Add([]byte(`{"loaded":true,"size":4511,"bytes":2290112,"usage":7402656,"maxmempool":300000000,"mempoolminfee":0.00001000,"minrelaytxfee":0.00001000}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var info bitcoind.GetMempoolInfoResult
+		bitcoind.DecodeResult(data, &info, true)
+	})
+}
+
+func FuzzGetIndexInfo(f *testing.F) {
+	// v24.0.1 with -txindex and -coinstatsindex, while the coinstats index syncs
+	f.Add([]byte(`{"txindex":{"synced":true,"best_block_height":800281},"coinstatsindex":{"synced":false,"best_block_height":612004}}`))
+	f.Add([]byte(`{"basic block filter index":{"synced":true,"best_block_height":800281}}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var info bitcoind.GetIndexInfoResponse
+		bitcoind.DecodeResult(data, &info, true)
+	})
+}
@@ -0,0 +1,279 @@
+package bitcoind
+
+// scantxoutset
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NewDescriptorDescriptors creates descriptors for output descriptor balance metrics
+func NewDescriptorDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_descriptor_unspent_amount"), "Total amount of unspent outputs matching the output descriptor in "+opts.AmountUnit(), []string{"chain", "descriptor"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_descriptor_unspents"), "Number of unspent outputs matching the output descriptor", []string{"chain", "descriptor"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_descriptor_scan_height"), "Block height at which the output descriptor was last scanned", []string{"chain", "descriptor"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_descriptor_last_scan_timestamp_seconds"), "UNIX epoch time at which the output descriptor was last scanned", []string{"chain", "descriptor"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_descriptor_scan_range_end"), "Highest child index scanned for the ranged output descriptor", []string{"chain", "descriptor"}, opts.ConstLabels),
+	}
+}
+
+// NewDescriptorCollector creates a collector for the balances of named output descriptors. Ranged descriptors
+// are derived up to gapLimit indexes past the highest index with an unspent output. Run must be called to
+// perform the scans
+func NewDescriptorCollector(client *rpcclient.Client, logger Logger, interval time.Duration, scans map[string]string, gapLimit int64, options ...Option) *DescriptorCollector {
+	opts := NewOptions(options...)
+
+	return &DescriptorCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Interval:    interval,
+		Scans:       scans,
+		GapLimit:    gapLimit,
+		Descriptors: NewDescriptorDescriptors(opts),
+		results:     map[string]descriptorResult{},
+		trigger:     make(chan struct{}, 1),
+	}
+}
+
+// DescriptorCollector scans the UTXO set for output descriptors in the background and builds metrics from the
+// last scan of each. scantxoutset reads the entire UTXO set and only one scan can run at a time, so it is never
+// called during a scrape. Only unspent outputs are found, so amounts received and later spent are not exported,
+// and addresses whose outputs have all been spent do not extend the scanned range
+type DescriptorCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Interval time.Duration
+
+	// Scans maps descriptor label values to output descriptors
+	Scans map[string]string
+
+	// GapLimit is the number of unused child indexes scanned past the highest used index of ranged descriptors
+	GapLimit int64
+
+	Descriptors []*prometheus.Desc
+
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	results map[string]descriptorResult
+}
+
+type descriptorResult struct {
+	chain   string
+	result  ScanTxOutSetResult
+	scanned time.Time
+
+	// end is the highest child index scanned for a ranged descriptor
+	end int64
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *DescriptorCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// ScanObject is a scantxoutset scan object
+type ScanObject struct {
+	Desc  string `json:"desc"`
+	Range int64  `json:"range,omitempty"`
+}
+
+// ScanTxOutSetCmd calls the scantxoutset RPC
+type ScanTxOutSetCmd struct {
+	Action      string
+	ScanObjects []ScanObject
+}
+
+func init() {
+	btcjson.MustRegisterCmd("scantxoutset", (*ScanTxOutSetCmd)(nil), btcjson.UsageFlag(0))
+}
+
+// ScanTxOutSetResult decodes the scantxoutset (v24.0.0) start action response
+type ScanTxOutSetResult struct {
+	Success     bool    `json:"success"`
+	TxOuts      int64   `json:"txouts"`
+	Height      int64   `json:"height"`
+	BestBlock   string  `json:"bestblock"`
+	TotalAmount float64 `json:"total_amount"`
+	Unspents    []struct {
+		TxID   string  `json:"txid"`
+		Vout   uint32  `json:"vout"`
+		Amount float64 `json:"amount"`
+		Height int64   `json:"height"`
+		Desc   string  `json:"desc"`
+	} `json:"unspents"`
+}
+
+// Trigger requests scans of every descriptor outside of the schedule, which start when the current scans, if
+// any, complete. It returns false if requested scans are already pending
+func (col *DescriptorCollector) Trigger() bool {
+	select {
+	case col.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run scans each descriptor immediately and then every Interval, or when triggered, until the context is canceled
+func (col *DescriptorCollector) Run(ctx context.Context) {
+	names := make([]string, 0, len(col.Scans))
+	for name := range col.Scans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for {
+		for _, name := range names {
+			if ctx.Err() != nil {
+				return
+			}
+
+			col.scan(name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(col.Interval):
+		case <-col.trigger:
+		}
+	}
+}
+
+// scan calls the scantxoutset RPC for a single descriptor and records its result. Ranged descriptors are
+// scanned again with an extended range until the last GapLimit indexes have no unspent outputs
+func (col *DescriptorCollector) scan(name string) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	object := ScanObject{Desc: col.Scans[name]}
+	ranged := IsRangedDescriptor(object.Desc)
+
+	// Start from the range that the last scan reached, as outputs below it may have been spent since
+	col.mu.Lock()
+	end := col.GapLimit - 1
+	if last, has := col.results[name]; has && last.end > end {
+		end = last.end
+	}
+	col.mu.Unlock()
+
+	for {
+		if ranged {
+			object.Range = end
+		}
+
+		col.Debug("Scanning UTXO set for descriptor", zap.String("descriptor", name), zap.Int64("range", object.Range))
+		data, err := col.Receive(col.SendCmd(&ScanTxOutSetCmd{"start", []ScanObject{object}}))
+		if err != nil {
+			LogRPCError(col.Logger, "scantxoutset", err, zap.String("descriptor", name))
+			return
+		}
+
+		var result ScanTxOutSetResult
+		err = col.Decode("scantxoutset", data, &result)
+
+		if err != nil {
+			col.Error("Failed to decode scantxoutset response", zap.String("descriptor", name), zap.Error(err))
+			return
+		}
+
+		if !result.Success {
+			col.Warn("scantxoutset was aborted", zap.String("descriptor", name))
+			return
+		}
+
+		if ranged {
+			highest := int64(-1)
+			for _, unspent := range result.Unspents {
+				if index := DerivationIndex(unspent.Desc); index > highest {
+					highest = index
+				}
+			}
+
+			if highest+col.GapLimit > end {
+				end = highest + col.GapLimit
+				continue
+			}
+		}
+
+		col.mu.Lock()
+		defer col.mu.Unlock()
+
+		col.results[name] = descriptorResult{chain.Chain, result, time.Now(), end}
+		return
+	}
+}
+
+// IsRangedDescriptor returns true if a descriptor contains a wildcard derivation step
+func IsRangedDescriptor(desc string) bool {
+	return strings.Contains(desc, "*")
+}
+
+// DerivationIndex returns the highest final child index in the key origins of an inferred descriptor, like
+// wpkh([d34db33f/0/5]03a1...), or -1 if it has no key origins
+func DerivationIndex(desc string) int64 {
+	index := int64(-1)
+
+	for _, origin := range strings.Split(desc, "[")[1:] {
+		end := strings.IndexByte(origin, ']')
+		if end < 0 {
+			continue
+		}
+
+		steps := strings.Split(origin[:end], "/")
+		if len(steps) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseInt(strings.TrimRight(steps[len(steps)-1], "'h"), 10, 64)
+		if err == nil && value > index {
+			index = value
+		}
+	}
+
+	return index
+}
+
+// Collect builds metrics from the last completed scan of each descriptor
+func (col *DescriptorCollector) Collect(out chan<- prometheus.Metric) {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	for name, scan := range col.results {
+		metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, col.Amount(scan.result.TotalAmount), scan.chain, name)
+		out <- col.Timestamped(metric, scan.scanned)
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(len(scan.result.Unspents)), scan.chain, name)
+		out <- col.Timestamped(metric, scan.scanned)
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(scan.result.Height), scan.chain, name)
+		out <- col.Timestamped(metric, scan.scanned)
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(scan.scanned.UnixNano())/1e9, scan.chain, name)
+		out <- col.Timestamped(metric, scan.scanned)
+
+		if IsRangedDescriptor(col.Scans[name]) {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, float64(scan.end), scan.chain, name)
+			out <- col.Timestamped(metric, scan.scanned)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package bitcoind_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDescriptorCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	var mu sync.Mutex
+	var ranges []int64
+	server.Handle("scantxoutset", func(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+		var objects []bitcoind.ScanObject
+		json.Unmarshal(params[1], &objects)
+
+		mu.Lock()
+		defer mu.Unlock()
+		ranges = append(ranges, objects[0].Range)
+
+		// Outputs are found at indexes 15 and 30, which is only within the range once it has been extended
+		total, unspents := 1.0, []bitcoindtest.Object{{"txid": "aa", "vout": 0, "amount": 1, "height": bitcoindtest.Height - 10, "desc": "wpkh([d34db33f/0/15]03aa)#abcdefgh"}}
+		if objects[0].Range >= 30 {
+			total, unspents = 1.5, append(unspents, bitcoindtest.Object{"txid": "bb", "vout": 1, "amount": 0.5, "height": bitcoindtest.Height, "desc": "wpkh([d34db33f/0/30']03bb)#abcdefgh"})
+		}
+
+		return bitcoindtest.Object{
+			"success": true, "txouts": 100, "height": bitcoindtest.Height, "bestblock": bitcoindtest.BlockHash, "total_amount": total, "unspents": unspents,
+		}, nil
+	})
+
+	scans := map[string]string{
+		"cold":   "addr(bc1qexample)",
+		"hot":    "wpkh(xpub6example/0/*)",
+		"change": "wpkh(xpub6example/1/*)",
+	}
+
+	col := bitcoind.NewDescriptorCollector(server.Client(t), bitcoindtest.Logger(t), time.Hour, scans, 20, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go col.Run(ctx)
+
+	// Wait for the first scan of every descriptor
+	var families []*dto.MetricFamily
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		families = bitcoindtest.Gather(t, col)
+		if _, has := bitcoindtest.Find(families, "bitcoind_descriptor_unspents", prometheus.Labels{"descriptor": "hot"}); has {
+			break
+		}
+	}
+
+	for _, name := range []string{"hot", "change"} {
+		labels := prometheus.Labels{"chain": bitcoindtest.Chain, "descriptor": name}
+		bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_unspent_amount", labels, 150000000)
+		bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_unspents", labels, 2)
+		bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_scan_height", labels, bitcoindtest.Height)
+		bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_scan_range_end", labels, 50)
+	}
+
+	labels := prometheus.Labels{"chain": bitcoindtest.Chain, "descriptor": "cold"}
+	bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_unspent_amount", labels, 100000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_descriptor_unspents", labels, 1)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_descriptor_scan_range_end", labels)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Scans run in order of their names, and only ranged descriptors have a range. Ranged descriptors are
+	// scanned again until the gap limit past the highest used index has been scanned
+	expected := []int64{19, 35, 50, 0, 19, 35, 50}
+	if len(ranges) != len(expected) {
+		t.Fatalf("scan ranges = %v, expected %v", ranges, expected)
+	}
+
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Fatalf("scan ranges = %v, expected %v", ranges, expected)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package bitcoind
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RPCErrors counts failed RPC calls by method and error class. It describes the exporter rather than
+// bitcoind, so it is not labeled by chain or --label values
+var RPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "bitcoind_exporter_rpc_errors_total",
+	Help: "Number of failed RPC calls by method and error code. JSON-RPC errors are labeled with bitcoind's numeric error code, and transport errors with a class such as connection_refused, timeout, auth or http_<status>",
+}, []string{"method", "code"})
+
+// IsMethodNotFound checks if an RPC call failed because the node does not implement the method, as for
+// wallet RPCs on a node running with -disablewallet
+func IsMethodNotFound(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code
+}
+
+// IsWalletNotFound checks if a wallet RPC call failed because the wallet is not loaded, as when it is
+// unloaded after being listed by listwallets
+func IsWalletNotFound(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCWalletNotFound
+}
+
+// IsUnreachable checks if an RPC call failed without a JSON-RPC response from the node, e.g. because the
+// connection was refused, timed out or was not authorized
+func IsUnreachable(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return err != nil && !errors.As(err, &rpcErr)
+}
+
+// RPCErrorCode classifies an RPC error for the code label of bitcoind_exporter_rpc_errors_total
+func RPCErrorCode(err error) string {
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) {
+		return strconv.Itoa(int(rpcErr.Code))
+	}
+
+	// rpcclient reports HTTP responses without a JSON-RPC body, e.g. 401 for bad credentials, by status code
+	var status int
+	if _, scanErr := fmt.Sscanf(err.Error(), "status code: %d", &status); scanErr == nil {
+		if status == 401 || status == 403 {
+			return "auth"
+		}
+
+		return "http_" + strconv.Itoa(status)
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_reset"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr), errors.As(err, &hostErr), errors.As(err, &recordErr):
+		return "tls"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+
+	// rpcclient's HTTP POST retries format the last transport error into a string
+	message := err.Error()
+	for _, class := range transportErrors {
+		if strings.Contains(message, class.match) {
+			return class.code
+		}
+	}
+
+	return "other"
+}
+
+var transportErrors = []struct{ match, code string }{
+	{"connection refused", "connection_refused"},
+	{"connection reset", "connection_reset"},
+	{"EOF", "connection_reset"},
+	{"no such host", "dns"},
+	{"x509:", "tls"},
+	{"tls:", "tls"},
+	{"i/o timeout", "timeout"},
+	{"deadline exceeded", "timeout"},
+}
@@ -0,0 +1,214 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ExternalFeeEstimate is a fee rate reported by an external source, and the confirmation target of the node's
+// estimate that it is compared with. Estimates without a target are exported without a comparison
+type ExternalFeeEstimate struct {
+	Name   string
+	Field  string
+	Target int64
+}
+
+// ExternalFeeEstimates are the fields of a mempool.space recommended fees response, in sat/vB. The next block
+// estimate is compared with a target of 2 blocks, which is the shortest target that estimatesmartfee supports
+var ExternalFeeEstimates = []ExternalFeeEstimate{
+	{"fastest", "fastestFee", 2},
+	{"half_hour", "halfHourFee", 3},
+	{"hour", "hourFee", 6},
+	{"economy", "economyFee", 0},
+	{"minimum", "minimumFee", 0},
+}
+
+// ExternalFeeSource reads recommended fee rates from an HTTP API with the response format of mempool.space's
+// /api/v1/fees/recommended
+type ExternalFeeSource struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// NewExternalFeeSource creates an ExternalFeeSource for a URL such as https://mempool.space/api/v1/fees/recommended
+func NewExternalFeeSource(url string, timeout time.Duration) *ExternalFeeSource {
+	return &ExternalFeeSource{url, &http.Client{Timeout: timeout}}
+}
+
+// Name returns the host of the source's URL, for metric labels
+func (source *ExternalFeeSource) Name() string {
+	parsed, err := url.Parse(source.URL)
+	if err != nil || parsed.Host == "" {
+		return source.URL
+	}
+
+	return parsed.Host
+}
+
+// Estimates requests recommended fee rates, returning them in sat/vB by response field
+func (source *ExternalFeeSource) Estimates() (map[string]float64, error) {
+	res, err := source.HTTP.Get(source.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, response: %q", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var estimates map[string]float64
+	err = json.Unmarshal(body, &estimates)
+
+	return estimates, err
+}
+
+// NewExternalFeeDescriptors creates descriptors for collected external fee estimate metrics
+func NewExternalFeeDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_external_fee_estimate"), "Fee rate in "+opts.FeeRateUnit()+" recommended by an external source", []string{"chain", "source", "estimate"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_external_fee_estimate_delta"), "Node's estimatesmartfee fee rate minus the external source's fee rate in "+opts.FeeRateUnit()+" for the estimate's confirmation target. A large difference indicates that the node's view of the mempool is skewed", []string{"chain", "source", "estimate", "target", "mode"}, opts.FeeLabels()),
+	}
+}
+
+// NewExternalFeeCollector creates a new prometheus.Collector comparing an external source's fee rates with
+// estimatesmartfee results
+func NewExternalFeeCollector(client *rpcclient.Client, logger Logger, source *ExternalFeeSource, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &ExternalFeeCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Source:      source,
+		Descriptors: NewExternalFeeDescriptors(opts),
+
+		Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_external_fee_estimate_failures_total"), Help: "Number of failed requests for fee rates from the external source", ConstLabels: opts.ConstLabels,
+		}, []string{"source"}),
+	}
+}
+
+// ExternalFeeCollector builds metrics from an external source's recommended fee rates and their differences
+// from estimatesmartfee RPC responses in each of FeeEstimateModes
+type ExternalFeeCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Source      *ExternalFeeSource
+	Descriptors []*prometheus.Desc
+
+	Failures *prometheus.CounterVec
+}
+
+// Inherit takes over the failure counter of a previous ExternalFeeCollector
+func (col *ExternalFeeCollector) Inherit(previous prometheus.Collector) {
+	if prev, ok := previous.(*ExternalFeeCollector); ok {
+		col.Failures = prev.Failures
+	}
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ExternalFeeCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+
+	col.Failures.Describe(out)
+}
+
+// Collect requests the external source's fee rates and the node's estimates for their targets, and builds
+// metrics from both
+func (col *ExternalFeeCollector) Collect(out chan<- prometheus.Metric) {
+	defer col.Failures.Collect(out)
+
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	// Send the node's estimates before waiting for the external source
+	futures := make([][]chan *rpcclient.Response, len(ExternalFeeEstimates))
+	for i, estimate := range ExternalFeeEstimates {
+		if estimate.Target == 0 {
+			continue
+		}
+
+		for _, mode := range FeeEstimateModes {
+			mode := mode
+			futures[i] = append(futures[i], col.SendCmd(btcjson.NewEstimateSmartFeeCmd(estimate.Target, &mode)))
+		}
+	}
+
+	name := col.Source.Name()
+
+	external, err := col.Source.Estimates()
+	if err != nil {
+		col.Warn("Failed to request external fee estimates", zap.String("source", name), zap.Error(err))
+		col.Failures.WithLabelValues(name).Inc()
+
+		return
+	}
+
+	for i, estimate := range ExternalFeeEstimates {
+		rate, has := external[estimate.Field]
+		if !has {
+			continue
+		}
+
+		// External rates are in sat/vB, and converted from BTC/kvB like RPC results
+		rate = col.FeeRate(rate / 1e5)
+
+		metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, rate, chain.Chain, name, estimate.Name)
+		out <- metric
+
+		target := strconv.FormatInt(estimate.Target, 10)
+
+		for j, mode := range FeeEstimateModes {
+			if estimate.Target == 0 {
+				break
+			}
+
+			data, err := col.Receive(futures[i][j])
+			if err != nil {
+				LogRPCError(col.Logger, "estimatesmartfee", err)
+				return
+			}
+
+			var result btcjson.EstimateSmartFeeResult
+			err = col.Decode("estimatesmartfee", data, &result)
+
+			if err != nil {
+				col.Error("Failed to decode estimatesmartfee response", zap.Error(err))
+				return
+			}
+
+			if result.FeeRate == nil {
+				continue
+			}
+
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, col.FeeRate(*result.FeeRate)-rate, chain.Chain, name, estimate.Name, target, strings.ToLower(string(mode)))
+			out <- metric
+		}
+	}
+
+	LastCollection.Mark()
+}
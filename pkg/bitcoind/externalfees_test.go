@@ -0,0 +1,51 @@
+package bitcoind_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestExternalFeeCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	fees := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"fastestFee":25,"halfHourFee":20,"hourFee":15,"economyFee":8,"minimumFee":4}`))
+	}))
+	t.Cleanup(fees.Close)
+
+	source := bitcoind.NewExternalFeeSource(fees.URL, time.Second)
+	col := bitcoind.NewExternalFeeCollector(server.Client(t), bitcoindtest.Logger(t), source, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	name := strings.TrimPrefix(fees.URL, "http://")
+	bitcoindtest.AssertValue(t, families, "bitcoind_external_fee_estimate", prometheus.Labels{"source": name, "estimate": "fastest"}, 25)
+	bitcoindtest.AssertValue(t, families, "bitcoind_external_fee_estimate", prometheus.Labels{"source": name, "estimate": "minimum"}, 4)
+
+	// The node estimates 20 sat/vB for every target
+	bitcoindtest.AssertValue(t, families, "bitcoind_external_fee_estimate_delta", prometheus.Labels{"estimate": "fastest", "target": "2", "mode": "economical"}, -5)
+	bitcoindtest.AssertValue(t, families, "bitcoind_external_fee_estimate_delta", prometheus.Labels{"estimate": "half_hour", "target": "3", "mode": "conservative"}, 0)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_external_fee_estimate_delta", prometheus.Labels{"estimate": "economy"})
+}
+
+func TestExternalFeeCollectorFailure(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	fees := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	t.Cleanup(fees.Close)
+
+	col := bitcoind.NewExternalFeeCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.NewExternalFeeSource(fees.URL, time.Second))
+	families := bitcoindtest.Gather(t, col)
+
+	name := strings.TrimPrefix(fees.URL, "http://")
+	bitcoindtest.AssertValue(t, families, "bitcoind_external_fee_estimate_failures_total", prometheus.Labels{"source": name}, 1)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_external_fee_estimate", nil)
+}
@@ -0,0 +1,144 @@
+package bitcoind
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// FeeEstimateTargets are the confirmation targets, in blocks, estimated by default
+var FeeEstimateTargets = []int64{2, 6, 12, 144}
+
+// FeeEstimateModes are the estimatesmartfee modes that are compared for each target
+var FeeEstimateModes = []btcjson.EstimateSmartFeeMode{btcjson.EstimateModeEconomical, btcjson.EstimateModeConservative}
+
+// NewFeeEstimateDescriptors creates descriptors for collected fee estimate metrics
+func NewFeeEstimateDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_fee_estimate"), "Fee rate in "+opts.FeeRateUnit()+" estimated for confirmation within the target number of blocks, by estimate mode", []string{"chain", "target", "mode"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_fee_estimate_blocks"), "Number of blocks for which the fee estimate was found, which may be more than the target if there is insufficient data for it", []string{"chain", "target", "mode"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_fee_estimate_mode_spread"), "Conservative minus economical fee rate estimate in "+opts.FeeRateUnit()+" for the target number of blocks", []string{"chain", "target"}, opts.FeeLabels()),
+	}
+}
+
+// NewFeeEstimateCollector creates a new prometheus.Collector for estimatesmartfee results at each confirmation target
+func NewFeeEstimateCollector(client *rpcclient.Client, logger Logger, targets []int64, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &FeeEstimateCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Targets:     targets,
+		Descriptors: NewFeeEstimateDescriptors(opts),
+
+		Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_fee_estimate_failures_total"), Help: "Number of fee estimates that failed or returned errors, such as insufficient data, instead of a fee rate", ConstLabels: opts.ConstLabels,
+		}, []string{"chain", "target", "mode"}),
+	}
+}
+
+// FeeEstimateCollector builds metrics from estimatesmartfee RPC responses in each of FeeEstimateModes
+type FeeEstimateCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Targets     []int64
+	Descriptors []*prometheus.Desc
+
+	Failures *prometheus.CounterVec
+}
+
+// Inherit takes over the failure counter of a previous FeeEstimateCollector
+func (col *FeeEstimateCollector) Inherit(previous prometheus.Collector) {
+	if prev, ok := previous.(*FeeEstimateCollector); ok {
+		col.Failures = prev.Failures
+	}
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *FeeEstimateCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+
+	col.Failures.Describe(out)
+}
+
+// Collect calls the estimatesmartfee RPC for each target and mode and builds metrics from its responses
+func (col *FeeEstimateCollector) Collect(out chan<- prometheus.Metric) {
+	defer col.Failures.Collect(out)
+
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	// Send every estimate before waiting for any responses
+	futures := make([][]chan *rpcclient.Response, len(col.Targets))
+	for i, target := range col.Targets {
+		for _, mode := range FeeEstimateModes {
+			mode := mode
+			futures[i] = append(futures[i], col.SendCmd(btcjson.NewEstimateSmartFeeCmd(target, &mode)))
+		}
+	}
+
+	for i, target := range col.Targets {
+		label := strconv.FormatInt(target, 10)
+		estimates := map[btcjson.EstimateSmartFeeMode]float64{}
+
+		for j, mode := range FeeEstimateModes {
+			name := strings.ToLower(string(mode))
+
+			// A failed estimate is counted, and the remaining targets and modes are still collected
+			data, err := col.Receive(futures[i][j])
+			if err != nil {
+				LogRPCError(col.Logger, "estimatesmartfee", err, zap.Int64("target", target), zap.String("mode", name))
+				col.Failures.WithLabelValues(chain.Chain, label, name).Inc()
+
+				continue
+			}
+
+			var result btcjson.EstimateSmartFeeResult
+			err = col.Decode("estimatesmartfee", data, &result)
+
+			if err != nil {
+				col.Error("Failed to decode estimatesmartfee response", zap.Int64("target", target), zap.String("mode", name), zap.Error(err))
+				col.Failures.WithLabelValues(chain.Chain, label, name).Inc()
+
+				continue
+			}
+
+			LastCollection.Mark()
+
+			if result.FeeRate == nil {
+				col.Debug("No fee estimate", zap.Int64("target", target), zap.String("mode", name), zap.Strings("errors", result.Errors))
+				col.Failures.WithLabelValues(chain.Chain, label, name).Inc()
+
+				continue
+			}
+
+			estimates[mode] = *result.FeeRate
+
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, col.FeeRate(*result.FeeRate), chain.Chain, label, name)
+			out <- metric
+
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(result.Blocks), chain.Chain, label, name)
+			out <- metric
+		}
+
+		economical, hasEconomical := estimates[btcjson.EstimateModeEconomical]
+		conservative, hasConservative := estimates[btcjson.EstimateModeConservative]
+
+		if hasEconomical && hasConservative {
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, col.FeeRate(conservative)-col.FeeRate(economical), chain.Chain, label)
+			out <- metric
+		}
+	}
+}
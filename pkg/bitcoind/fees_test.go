@@ -0,0 +1,72 @@
+package bitcoind_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// estimateSmartFee answers estimatesmartfee with a fee rate that depends on the mode, and without an estimate
+// for targets over 144 blocks
+func estimateSmartFee(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	var target int64
+	var mode string
+	json.Unmarshal(params[0], &target)
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &mode)
+	}
+
+	if target > 144 {
+		return bitcoindtest.Object{"errors": []string{"Insufficient data or no feerate found"}, "blocks": target}, nil
+	}
+
+	if mode == "CONSERVATIVE" {
+		return bitcoindtest.Object{"feerate": 0.0003, "blocks": target}, nil
+	}
+
+	return bitcoindtest.Object{"feerate": 0.0002, "blocks": target}, nil
+}
+
+func TestFeeEstimateCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Handle("estimatesmartfee", estimateSmartFee)
+
+	col := bitcoind.NewFeeEstimateCollector(server.Client(t), bitcoindtest.Logger(t), []int64{2, 1008}, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate", prometheus.Labels{"target": "2", "mode": "economical"}, 20)
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate", prometheus.Labels{"target": "2", "mode": "conservative"}, 30)
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_blocks", prometheus.Labels{"target": "2", "mode": "economical"}, 2)
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_mode_spread", prometheus.Labels{"target": "2"}, 10)
+
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_fee_estimate", prometheus.Labels{"target": "1008"})
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_failures_total", prometheus.Labels{"target": "1008", "mode": "economical"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_failures_total", prometheus.Labels{"target": "1008", "mode": "conservative"}, 1)
+}
+
+func TestFeeEstimateCollectorRPCError(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Handle("estimatesmartfee", func(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+		var target int64
+		json.Unmarshal(params[0], &target)
+
+		if target == 2 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid conf_target")
+		}
+
+		return estimateSmartFee(params)
+	})
+
+	col := bitcoind.NewFeeEstimateCollector(server.Client(t), bitcoindtest.Logger(t), []int64{2, 6}, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	// The failed target is counted, and later targets are still collected
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_failures_total", prometheus.Labels{"target": "2", "mode": "economical"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate_failures_total", prometheus.Labels{"target": "2", "mode": "conservative"}, 1)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_fee_estimate", prometheus.Labels{"target": "2"})
+	bitcoindtest.AssertValue(t, families, "bitcoind_fee_estimate", prometheus.Labels{"target": "6", "mode": "economical"}, 20)
+}
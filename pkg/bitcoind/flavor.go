@@ -0,0 +1,142 @@
+package bitcoind
+
+import (
+	"net"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Node implementations, selected by Options.Flavor
+const (
+	FlavorCore     = "core"
+	FlavorBtcd     = "btcd"
+	FlavorKnots    = "knots"
+	FlavorElements = "elements"
+)
+
+// Flavors lists the supported node implementations
+var Flavors = []string{FlavorCore, FlavorBtcd, FlavorKnots, FlavorElements}
+
+// DefaultFeeAsset is the label of the policy asset in which Elements fees are paid, L-BTC on Liquid
+const DefaultFeeAsset = "bitcoin"
+
+// IsFlavor checks if a name is one of Flavors
+func IsFlavor(name string) bool {
+	return contains(Flavors, name)
+}
+
+// WithFlavor adjusts RPCs and decoders for a node implementation other than Bitcoin Core
+func WithFlavor(flavor string) Option {
+	return func(opts *Options) {
+		opts.Flavor = flavor
+	}
+}
+
+// Core returns true if the node is Bitcoin Core or derived from it, and so returns response fields that btcd does not
+func (opts Options) Core() bool {
+	return opts.Flavor != FlavorBtcd
+}
+
+// WithFeeAsset labels fee metrics from Elements nodes with the asset that fees are paid in
+func WithFeeAsset(asset string) Option {
+	return func(opts *Options) {
+		opts.FeeAsset = asset
+	}
+}
+
+// Knots returns true if the node is Bitcoin Knots, which adds policy fields to getmempoolinfo and getpeerinfo
+func (opts Options) Knots() bool {
+	return opts.Flavor == FlavorKnots
+}
+
+// Elements returns true if the node runs an Elements sidechain such as Liquid, whose blocks are signed rather
+// than mined, and whose amounts are denominated in assets
+func (opts Options) Elements() bool {
+	return opts.Flavor == FlavorElements
+}
+
+// FeeLabels returns constant labels for fee metric descriptors. Fees on Elements chains are paid in the
+// policy asset, which is added as an asset label
+func (opts Options) FeeLabels() prometheus.Labels {
+	if !opts.Elements() {
+		return opts.ConstLabels
+	}
+
+	asset := opts.FeeAsset
+	if asset == "" {
+		asset = DefaultFeeAsset
+	}
+
+	labels := prometheus.Labels{"asset": asset}
+	for name, value := range opts.ConstLabels {
+		labels[name] = value
+	}
+
+	return labels
+}
+
+// AssetLabelNames appends an asset label to variable label names for Elements nodes, whose wallets hold multiple assets
+func (opts Options) AssetLabelNames(names ...string) []string {
+	if opts.Elements() {
+		return append(names, "asset")
+	}
+
+	return names
+}
+
+// btcdChains maps btcd's network names to Bitcoin Core's chain names
+var btcdChains = map[string]string{
+	"mainnet":  "main",
+	"testnet3": "test",
+}
+
+// ChainName returns Bitcoin Core's name for a btcd network, so that chain labels match between node implementations
+func ChainName(network string) string {
+	if name, has := btcdChains[network]; has {
+		return name
+	}
+
+	return network
+}
+
+// FromBtcd converts a btcd getpeerinfo result to Bitcoin Core's units. btcd reports ping times in
+// microseconds and its feefilter in sat/kB, and does not report the peer's network
+func (peer GetPeerInfoResult) FromBtcd() GetPeerInfoResult {
+	peer.PingTime /= 1e6
+	peer.PingWait /= 1e6
+	peer.MinFeeFilter = float64(peer.FeeFilter) / 1e8
+
+	if peer.Network == "" {
+		peer.Network = AddrNetwork(peer.Addr)
+	}
+
+	return peer
+}
+
+// AddrNetwork returns the getpeerinfo network name for a host:port address
+func AddrNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	switch {
+	case strings.HasSuffix(host, ".onion"):
+		return "onion"
+	case strings.HasSuffix(host, ".i2p"):
+		return "i2p"
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified():
+		return "not_publicly_routable"
+	case ip.To4() != nil:
+		return "ipv4"
+	default:
+		return "ipv6"
+	}
+}
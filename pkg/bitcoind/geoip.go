@@ -0,0 +1,43 @@
+package bitcoind
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoIP resolves peer addresses to locations with a MaxMind GeoLite2 or GeoIP2 Country or City database
+type GeoIP struct {
+	*geoip2.Reader
+}
+
+// OpenGeoIP opens a MaxMind database file
+func OpenGeoIP(path string) (*GeoIP, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GeoIP{reader}, nil
+}
+
+// Locate returns ISO country and continent codes for a peer's host:port address. Empty
+// strings are returned for addresses that can not be resolved, e.g. onion and i2p peers
+func (geo *GeoIP) Locate(addr string) (country, continent string) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return
+	}
+
+	record, err := geo.Country(ip)
+	if err != nil {
+		return
+	}
+
+	return record.Country.IsoCode, record.Continent.Code
+}
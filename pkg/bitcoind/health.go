@@ -0,0 +1,29 @@
+package bitcoind
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LastCollection records the time of the most recent successful RPC collection by any collector
+var LastCollection Timestamp
+
+// Timestamp is a time value that can be updated and read concurrently
+type Timestamp struct {
+	nanos int64
+}
+
+// Mark sets the timestamp to the current time
+func (ts *Timestamp) Mark() {
+	atomic.StoreInt64(&ts.nanos, time.Now().UnixNano())
+}
+
+// Time returns the last marked time, or the zero time if it has never been marked
+func (ts *Timestamp) Time() time.Time {
+	nanos := atomic.LoadInt64(&ts.nanos)
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
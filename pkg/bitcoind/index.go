@@ -3,7 +3,8 @@ package bitcoind
 // getindexinfo
 
 import (
-	"encoding/json"
+	"io/fs"
+	"path/filepath"
 
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/rpcclient"
@@ -11,30 +12,58 @@ import (
 	"go.uber.org/zap"
 )
 
-// IndexDescriptors contains cached descriptor values for collected index metrics
-var IndexDescriptors = []*prometheus.Desc{
-	prometheus.NewDesc("bitcoind_index_best_block_height", "Block height to which the index is synced", []string{"chain", "index"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_index_synced", "Whether the index is synced or not", []string{"chain", "index"}, prometheus.Labels{}),
+// NewIndexDescriptors creates descriptors for collected index metrics
+func NewIndexDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_index_best_block_height"), "Block height to which the index is synced", []string{"chain", "index"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_index_synced"), "Whether the index is synced or not", []string{"chain", "index"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_index_enabled"), "Whether the index is enabled on the node or not", []string{"chain", "index"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_index_size_on_disk", "bitcoind_index_size_on_disk_bytes"), "Size of the index's files in the node's data directory. Only reported if the data directory is set", []string{"chain", "index"}, opts.ConstLabels),
+	}
 }
 
 // NewIndexCollector creates a new prometheus.Collector for getindexinfo properties
-func NewIndexCollector(client *rpcclient.Client, logger *zap.Logger) prometheus.Collector {
-	return &IndexCollector{client, logger}
+func NewIndexCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &IndexCollector{client, logger, opts, NewIndexDescriptors(opts)}
 }
 
 // IndexCollector builds metrics from getindexinfo RPC responses
 type IndexCollector struct {
 	*rpcclient.Client
-	*zap.Logger
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
 }
 
 // Describe returns the collector's metric descriptor set
 func (col *IndexCollector) Describe(out chan<- *prometheus.Desc) {
-	for _, desc := range IndexDescriptors {
+	for _, desc := range col.Descriptors {
 		out <- desc
 	}
 }
 
+// KnownIndexes are always reported by bitcoind_index_enabled, whether or not the node has enabled them
+var KnownIndexes = []string{"txindex", "coinstatsindex", "basic block filter index"}
+
+// IndexDirs are the directories of indexes in a chain's data directory
+var IndexDirs = map[string]string{
+	"txindex":                  filepath.Join("indexes", "txindex"),
+	"coinstatsindex":           filepath.Join("indexes", "coinstats"),
+	"basic block filter index": filepath.Join("indexes", "blockfilter", "basic"),
+}
+
+// ChainDirs are the subdirectories of the data directory in which bitcoind stores each chain's files
+var ChainDirs = map[string]string{
+	"main":     "",
+	"test":     "testnet3",
+	"testnet4": "testnet4",
+	"signet":   "signet",
+	"regtest":  "regtest",
+}
+
 // GetIndexInfoCmd calls the getindexinfo RPC
 type GetIndexInfoCmd struct {
 	IndexName string `json:"index_name"`
@@ -50,40 +79,119 @@ type GetIndexInfoResponse map[string]struct {
 	BestBlockHeight int64 `json:"best_block_height"`
 }
 
+// Validate checks that every index is named and has a block height that is not negative
+func (info GetIndexInfoResponse) Validate() error {
+	for name, props := range info {
+		if name == "" {
+			return &ValidationError{"index", "empty index name"}
+		}
+
+		if err := validateCount(name+".best_block_height", props.BestBlockHeight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Collect calls the getindexinfo RPC and builds metrics from its response properties
 func (col *IndexCollector) Collect(out chan<- prometheus.Metric) {
-	chain, err := col.GetBlockChainInfo()
+	// getindexinfo was added in v0.21.0
+	if !col.Supports(Version0_21) {
+		return
+	}
+
+	chain, err := col.BlockChainInfo(col.Client)
 	if err != nil {
-		col.Error("RPC call getblockchaininfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getblockchaininfo", err)
 		return
 	}
 
-	data, err := rpcclient.ReceiveFuture(col.SendCmd(&GetIndexInfoCmd{}))
+	data, err := col.Receive(col.SendCmd(&GetIndexInfoCmd{}))
 	if err != nil {
-		col.Error("RPC call getindexinfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getindexinfo", err)
 		return
 	}
 
 	var info GetIndexInfoResponse
-	err = json.Unmarshal(data, &info)
+	err = col.Decode("getindexinfo", data, &info)
 
 	if err != nil {
 		col.Error("Failed to decode getindexinfo response", zap.Error(err))
 		return
 	}
 
+	LastCollection.Mark()
+
 	var metric prometheus.Metric
 
 	for name, props := range info {
-		metric, _ = prometheus.NewConstMetric(IndexDescriptors[0], prometheus.CounterValue, float64(props.BestBlockHeight), chain.Chain, name)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[0], col.HeightType(), float64(props.BestBlockHeight), chain.Chain, name)
 		out <- metric
 
 		if props.Synced {
-			metric, _ = prometheus.NewConstMetric(IndexDescriptors[1], prometheus.UntypedValue, 1, chain.Chain, name)
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, 1, chain.Chain, name)
 		} else {
-			metric, _ = prometheus.NewConstMetric(IndexDescriptors[1], prometheus.UntypedValue, 1, chain.Chain, name)
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, 0, chain.Chain, name)
 		}
+		out <- metric
 
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, 1, chain.Chain, name)
+		out <- metric
+
+		if col.DataDir == "" {
+			continue
+		}
+
+		dir, known := IndexDirs[name]
+		chainDir, hasChain := ChainDirs[chain.Chain]
+		if !known || !hasChain {
+			continue
+		}
+
+		path := filepath.Join(col.DataDir, chainDir, dir)
+
+		size, err := DirSize(path)
+		if err != nil {
+			col.Warn("Unable to read index size", zap.String("index", name), zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(size), chain.Chain, name)
+		out <- metric
+	}
+
+	for _, name := range KnownIndexes {
+		if _, has := info[name]; has {
+			continue
+		}
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, 0, chain.Chain, name)
 		out <- metric
 	}
 }
+
+// DirSize returns the total size of the regular files in a directory and its subdirectories
+func DirSize(path string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
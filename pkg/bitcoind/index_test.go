@@ -0,0 +1,60 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestIndexCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("getindexinfo", bitcoindtest.Object{
+		"txindex":                  bitcoindtest.Object{"synced": true, "best_block_height": bitcoindtest.Height},
+		"basic block filter index": bitcoindtest.Object{"synced": false, "best_block_height": 700000},
+	})
+
+	col := bitcoind.NewIndexCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	txindex := prometheus.Labels{"chain": bitcoindtest.Chain, "index": "txindex"}
+	filters := prometheus.Labels{"chain": bitcoindtest.Chain, "index": "basic block filter index"}
+	coinstats := prometheus.Labels{"chain": bitcoindtest.Chain, "index": "coinstatsindex"}
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_synced", txindex, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_synced", filters, 0)
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_best_block_height", filters, 700000)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_enabled", txindex, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_enabled", filters, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_index_enabled", coinstats, 0)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_index_synced", coinstats)
+}
+
+func TestIndexCollectorNoIndexes(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("getindexinfo", bitcoindtest.Object{})
+
+	col := bitcoind.NewIndexCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	for _, name := range bitcoind.KnownIndexes {
+		bitcoindtest.AssertValue(t, families, "bitcoind_index_enabled", prometheus.Labels{"chain": bitcoindtest.Chain, "index": name}, 0)
+	}
+}
+
+func TestIndexCollectorUnsupported(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version0_19)
+
+	col := bitcoind.NewIndexCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithVersion(bitcoind.Version0_19))
+	families := bitcoindtest.Gather(t, col)
+
+	if len(families) != 0 {
+		t.Errorf("gathered %d metric families from a node without getindexinfo", len(families))
+	}
+
+	if calls := server.Calls("getindexinfo"); calls != 0 {
+		t.Errorf("getindexinfo called %d times", calls)
+	}
+}
@@ -1,7 +1,7 @@
 package bitcoind
 
 import (
-	"encoding/json"
+	"fmt"
 
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/rpcclient"
@@ -9,34 +9,46 @@ import (
 	"go.uber.org/zap"
 )
 
-// MempoolDescriptors contains cached descriptor values for collected mempool metrics
-var MempoolDescriptors = []*prometheus.Desc{
-	prometheus.NewDesc("bitcoind_mempool_size", "Current mempool transaction count", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_bytes", "Sum of all virtual transaction sizes as defined in BIP 141. Differs from actual serialized size because witness data is discounted", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_usage", "Total memory usage for the mempool", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_total_fee", "Total fees for the mempool in BTC, ignoring modified fees through prioritisetransaction", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_max_bytes", "Maximum memory usage for the mempool", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_min_fee", "Minimum fee rate in BTC/kvB for transactions to be accepted. Is the maximum of minrelaytxfee and minimum mempool fee", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_min_relay_tx_fee", "Current minimum relay fee for transactions", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_incremental_relay_fee", "Minimum fee rate increment for mempool limiting or replacement in BTC/kvB", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_unbroadcast_count", "Current number of transactions that haven't passed initial broadcast yet", []string{"chain"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_mempool_fullrbf", "True if the mempool accepts RBF without replaceability signaling inspection", []string{"chain"}, prometheus.Labels{}),
+// NewMempoolDescriptors creates descriptors for collected mempool metrics
+func NewMempoolDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_size"), "Current mempool transaction count", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_bytes"), "Sum of all virtual transaction sizes as defined in BIP 141. Differs from actual serialized size because witness data is discounted", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_mempool_usage", "bitcoind_mempool_usage_bytes"), "Total memory usage for the mempool", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_total_fee"), "Total fees for the mempool in "+opts.AmountUnit()+", ignoring modified fees through prioritisetransaction", []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_max_bytes"), "Maximum memory usage for the mempool", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_min_fee"), "Minimum fee rate in "+opts.FeeRateUnit()+" for transactions to be accepted. Is the maximum of minrelaytxfee and minimum mempool fee", []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_min_relay_tx_fee"), "Current minimum relay fee rate in "+opts.FeeRateUnit()+" for transactions", []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_incremental_relay_fee"), "Minimum fee rate increment for mempool limiting or replacement in "+opts.FeeRateUnit(), []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_unbroadcast_count"), "Current number of transactions that haven't passed initial broadcast yet", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_fullrbf"), "True if the mempool accepts RBF without replaceability signaling inspection", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_loaded"), "True if the initial load of the mempool from mempool.dat has completed", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_rbf_policy_info"), "Replace-by-fee policy of a Bitcoin Knots mempool: optin, always or never", []string{"chain", "policy"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_permit_bare_multisig"), "True if a Bitcoin Knots mempool relays non-P2SH multisig outputs", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_max_datacarrier_size"), "Largest OP_RETURN data carrier output relayed by a Bitcoin Knots mempool, in bytes", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_package_limit"), "Ancestor and descendant package limits of a Bitcoin Knots mempool. Counts are in transactions and sizes in kvB", []string{"chain", "limit"}, opts.ConstLabels),
+	}
 }
 
 // NewMempoolCollector creates a new prometheus.Collector for getmempoolinfo properties
-func NewMempoolCollector(client *rpcclient.Client, logger *zap.Logger) prometheus.Collector {
-	return &MempoolCollector{client, logger}
+func NewMempoolCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &MempoolCollector{client, logger, opts, NewMempoolDescriptors(opts)}
 }
 
 // MempoolCollector builds metrics from getmempoolinfo RPC responses
 type MempoolCollector struct {
 	*rpcclient.Client
-	*zap.Logger
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
 }
 
 // Describe returns the collector's metric descriptor set
 func (col *MempoolCollector) Describe(out chan<- *prometheus.Desc) {
-	for _, desc := range MempoolDescriptors {
+	for _, desc := range col.Descriptors {
 		out <- desc
 	}
 }
@@ -55,61 +67,199 @@ type GetMempoolInfoResult struct {
 	MinRelayTXFee       float64 `json:"minrelaytxfee"`
 	IncrementalRelayFee float64 `json:"incrementalrelayfee"`
 	UnbroadcastCount    int64   `json:"unbroadcastcount"`
+
+	// Bitcoin Knots policy fields, which are nil if they are not reported
+	RBFPolicy            *string `json:"rbf_policy"`
+	PermitBareMultisig   *bool   `json:"permitbaremultisig"`
+	MaxDataCarrierSize   *int64  `json:"maxdatacarriersize"`
+	LimitAncestorCount   *int64  `json:"limitancestorcount"`
+	LimitAncestorSize    *int64  `json:"limitancestorsize"`
+	LimitDescendantCount *int64  `json:"limitdescendantcount"`
+	LimitDescendantSize  *int64  `json:"limitdescendantsize"`
+}
+
+// Validate checks that counts, sizes and fees are not negative, that a non-empty mempool has a size in bytes,
+// and that the mempool's minimum fee is not below the minimum relay fee
+func (info GetMempoolInfoResult) Validate() error {
+	for field, value := range map[string]int64{"size": info.Size, "bytes": info.Bytes, "usage": info.Usage, "maxmempool": info.MaxBytes, "unbroadcastcount": info.UnbroadcastCount} {
+		if err := validateCount(field, value); err != nil {
+			return err
+		}
+	}
+
+	for field, value := range map[string]float64{"total_fee": info.TotalFee, "mempoolminfee": info.MinFee, "minrelaytxfee": info.MinRelayTXFee, "incrementalrelayfee": info.IncrementalRelayFee} {
+		if err := validateAmount(field, value); err != nil {
+			return err
+		}
+	}
+
+	if info.Size > 0 && info.Bytes == 0 {
+		return &ValidationError{"bytes", fmt.Sprintf("zero with %d transactions", info.Size)}
+	}
+
+	if info.MinFee < info.MinRelayTXFee {
+		return &ValidationError{"mempoolminfee", fmt.Sprintf("%v is below minrelaytxfee %v", info.MinFee, info.MinRelayTXFee)}
+	}
+
+	return nil
+}
+
+// MempoolEntry unmarshals a transaction from the RPC v24.0.0 verbose getrawmempool and getmempoolentry responses
+type MempoolEntry struct {
+	VSize  int64 `json:"vsize"`
+	Weight int64 `json:"weight"`
+	Time   int64 `json:"time"`
+	Height int64 `json:"height"`
+
+	DescendantCount int64 `json:"descendantcount"`
+	DescendantSize  int64 `json:"descendantsize"`
+	AncestorCount   int64 `json:"ancestorcount"`
+	AncestorSize    int64 `json:"ancestorsize"`
+
+	Fees struct {
+		Base       float64 `json:"base"`
+		Modified   float64 `json:"modified"`
+		Ancestor   float64 `json:"ancestor"`
+		Descendant float64 `json:"descendant"`
+	} `json:"fees"`
+
+	Depends []string `json:"depends"`
+	SpentBy []string `json:"spentby"`
+
+	BIP125Replaceable bool `json:"bip125-replaceable"`
+	Unbroadcast       bool `json:"unbroadcast"`
+}
+
+// RawMempoolVerbose calls the getrawmempool RPC with verbose output, returning mempool entries by txid
+func (opts Options) RawMempoolVerbose(client *rpcclient.Client) (map[string]MempoolEntry, error) {
+	data, err := opts.Receive(client.SendCmd(btcjson.NewGetRawMempoolCmd(btcjson.Bool(true))))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]MempoolEntry
+	err = opts.Decode("getrawmempool", data, &entries)
+
+	return entries, err
 }
 
 // Collect calls the getmempoolinfo RPC and builds metrics from its response properties
 func (col *MempoolCollector) Collect(out chan<- prometheus.Metric) {
-	chain, err := col.GetBlockChainInfo()
+	chain, err := col.BlockChainInfo(col.Client)
 	if err != nil {
-		col.Error("RPC call getblockchaininfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getblockchaininfo", err)
 		return
 	}
 
-	data, err := rpcclient.ReceiveFuture(col.SendCmd(&btcjson.GetMempoolInfoCmd{}))
+	data, err := col.MempoolInfo(col.Client)
 	if err != nil {
-		col.Error("RPC call getmempoolinfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getmempoolinfo", err)
 		return
 	}
 
 	var info GetMempoolInfoResult
-	err = json.Unmarshal(data, &info)
+	err = col.Decode("getmempoolinfo", data, &info)
 
 	if err != nil {
 		col.Error("Failed to decode getmempoolinfo response", zap.Error(err))
 		return
 	}
 
-	metric, _ := prometheus.NewConstMetric(MempoolDescriptors[0], prometheus.GaugeValue, float64(info.Size), chain.Chain)
-	out <- metric
+	LastCollection.Mark()
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[1], prometheus.GaugeValue, float64(info.Bytes), chain.Chain)
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(info.Size), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[2], prometheus.GaugeValue, float64(info.Usage), chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(info.Bytes), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[3], prometheus.GaugeValue, info.TotalFee, chain.Chain)
-	out <- metric
+	// btcd only reports size and bytes
+	if !col.Core() {
+		return
+	}
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[4], prometheus.GaugeValue, float64(info.MaxBytes), chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(info.Usage), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[5], prometheus.GaugeValue, info.MinFee, chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, col.Amount(info.TotalFee), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[6], prometheus.GaugeValue, info.MinRelayTXFee, chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, float64(info.MaxBytes), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[7], prometheus.GaugeValue, info.IncrementalRelayFee, chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[5], prometheus.GaugeValue, col.FeeRate(info.MinFee), chain.Chain)
 	out <- metric
 
-	metric, _ = prometheus.NewConstMetric(MempoolDescriptors[8], prometheus.GaugeValue, float64(info.UnbroadcastCount), chain.Chain)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[6], prometheus.GaugeValue, col.FeeRate(info.MinRelayTXFee), chain.Chain)
 	out <- metric
 
-	if info.FullRBF {
-		metric, _ = prometheus.NewConstMetric(MempoolDescriptors[9], prometheus.UntypedValue, 1, chain.Chain)
-	} else {
-		metric, _ = prometheus.NewConstMetric(MempoolDescriptors[9], prometheus.UntypedValue, 0, chain.Chain)
+	// unbroadcastcount was added in v0.21.0
+	if col.Supports(Version0_21) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[8], prometheus.GaugeValue, float64(info.UnbroadcastCount), chain.Chain)
+		out <- metric
+	}
+
+	// incrementalrelayfee and fullrbf were added in v24.0.0
+	if col.Supports(Version24) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[7], prometheus.GaugeValue, col.FeeRate(info.IncrementalRelayFee), chain.Chain)
+		out <- metric
+
+		if info.FullRBF {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[9], col.BoolType(), 1, chain.Chain)
+		} else {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[9], col.BoolType(), 0, chain.Chain)
+		}
+		out <- metric
+	}
+
+	// loaded was added in v0.19.0
+	if col.Supports(Version0_19) {
+		if info.Loaded {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[10], prometheus.GaugeValue, 1, chain.Chain)
+		} else {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[10], prometheus.GaugeValue, 0, chain.Chain)
+		}
+		out <- metric
+	}
+
+	if col.Knots() {
+		col.collectKnots(out, chain.Chain, info)
+	}
+}
+
+// collectKnots builds metrics from the policy fields that Bitcoin Knots adds to getmempoolinfo
+func (col *MempoolCollector) collectKnots(out chan<- prometheus.Metric, chain string, info GetMempoolInfoResult) {
+	if info.RBFPolicy != nil {
+		metric, _ := prometheus.NewConstMetric(col.Descriptors[11], prometheus.GaugeValue, 1, chain, *info.RBFPolicy)
+		out <- metric
+	}
+
+	if info.PermitBareMultisig != nil {
+		if *info.PermitBareMultisig {
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[12], col.BoolType(), 1, chain)
+			out <- metric
+		} else {
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[12], col.BoolType(), 0, chain)
+			out <- metric
+		}
+	}
+
+	if info.MaxDataCarrierSize != nil {
+		metric, _ := prometheus.NewConstMetric(col.Descriptors[13], prometheus.GaugeValue, float64(*info.MaxDataCarrierSize), chain)
+		out <- metric
+	}
+
+	limits := map[string]*int64{
+		"ancestor_count":   info.LimitAncestorCount,
+		"ancestor_size":    info.LimitAncestorSize,
+		"descendant_count": info.LimitDescendantCount,
+		"descendant_size":  info.LimitDescendantSize,
+	}
+
+	for limit, value := range limits {
+		if value != nil {
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[14], prometheus.GaugeValue, float64(*value), chain, limit)
+			out <- metric
+		}
 	}
-	out <- metric
 }
@@ -0,0 +1,99 @@
+package bitcoind_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMempoolCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewMempoolCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithVersion(bitcoind.Version24))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_size", chain, 5000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_bytes", chain, 2000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_total_fee", chain, 0.5)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_min_fee", chain, 0.00001)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_loaded", chain, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_fullrbf", chain, 0)
+}
+
+func TestMempoolCollectorVersionGating(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version0_19)
+
+	col := bitcoind.NewMempoolCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithVersion(bitcoind.Version0_19))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_size", chain, 5000)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_mempool_unbroadcast_count", chain)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_mempool_fullrbf", chain)
+}
+
+func TestMempoolCollectorSatUnits(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewMempoolCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_total_fee", chain, 50000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_min_fee", chain, 1)
+}
+
+// mempoolEntries are getrawmempool verbose entries: a parent and child pair, and an unbroadcast transaction
+func mempoolEntries(now time.Time) bitcoindtest.Object {
+	return bitcoindtest.Object{
+		"aa": bitcoindtest.Object{"vsize": 200, "time": now.Add(-time.Hour).Unix(), "ancestorcount": 1, "ancestorsize": 200, "descendantcount": 2, "descendantsize": 350, "unbroadcast": false},
+		"bb": bitcoindtest.Object{"vsize": 150, "time": now.Add(-time.Minute).Unix(), "ancestorcount": 2, "ancestorsize": 350, "descendantcount": 1, "descendantsize": 150, "unbroadcast": false},
+		"cc": bitcoindtest.Object{"vsize": 120, "time": now.Add(-10 * time.Minute).Unix(), "ancestorcount": 1, "ancestorsize": 120, "descendantcount": 1, "descendantsize": 120, "unbroadcast": true},
+	}
+}
+
+func TestUnbroadcastCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("getrawmempool", mempoolEntries(time.Now()))
+
+	col := bitcoind.NewUnbroadcastCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_unbroadcast_vsize", chain, 120)
+
+	age, _ := bitcoindtest.Value(families, "bitcoind_mempool_unbroadcast_oldest_age_seconds", chain)
+	if age < 600 || age > 660 {
+		t.Errorf("oldest unbroadcast transaction age = %v, expected about 600", age)
+	}
+}
+
+func TestMempoolAncestryCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("getrawmempool", mempoolEntries(time.Now()))
+
+	col := bitcoind.NewMempoolAncestryCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	for _, name := range []string{"bitcoind_mempool_ancestor_count", "bitcoind_mempool_descendant_count", "bitcoind_mempool_ancestor_size", "bitcoind_mempool_descendant_size"} {
+		metric, has := bitcoindtest.Find(families, name, chain)
+		if !has || metric.Histogram == nil {
+			t.Errorf("no histogram %s", name)
+			continue
+		}
+
+		if count := metric.Histogram.GetSampleCount(); count != 3 {
+			t.Errorf("%s sample count = %d, expected 3", name, count)
+		}
+	}
+
+	metric, _ := bitcoindtest.Find(families, "bitcoind_mempool_ancestor_count", chain)
+	if sum := metric.GetHistogram().GetSampleSum(); sum != 4 {
+		t.Errorf("ancestor count sum = %v, expected 4", sum)
+	}
+}
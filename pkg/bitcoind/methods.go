@@ -0,0 +1,83 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// Methods is a set of RPC methods supported by a node. A nil set supports every method
+type Methods map[string]bool
+
+// GetMethods calls the help RPC and returns the methods that it lists. Wallet methods are
+// only listed when the node has wallet support enabled
+func GetMethods(client *rpcclient.Client) (Methods, error) {
+	data, err := rpcclient.ReceiveFuture(client.SendCmd(btcjson.NewHelpCmd(nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	var text string
+	err = json.Unmarshal(data, &text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Methods are listed one per line with their arguments, under "== Category ==" headings
+	methods := Methods{}
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "==") {
+			continue
+		}
+
+		methods[fields[0]] = true
+	}
+
+	return methods, nil
+}
+
+// Supports returns true if every one of the given methods is in the set, or if the set is nil
+func (methods Methods) Supports(names ...string) bool {
+	if methods == nil {
+		return true
+	}
+
+	for _, name := range names {
+		if !methods[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal returns true if both sets have the same methods. A nil set is only equal to another nil set
+func (methods Methods) Equal(other Methods) bool {
+	if (methods == nil) != (other == nil) || len(methods) != len(other) {
+		return false
+	}
+
+	for name := range methods {
+		if !other[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Missing returns the given methods that are not in the set
+func (methods Methods) Missing(names ...string) []string {
+	var missing []string
+	for _, name := range names {
+		if !methods.Supports(name) {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
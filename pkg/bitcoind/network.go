@@ -0,0 +1,218 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// LocalServices lists the service names reported by getnetworkinfo since v0.21.0, which are always exported
+// in local service metrics. Services that are not in the list are exported when the node advertises them
+var LocalServices = []string{"NETWORK", "BLOOM", "WITNESS", "COMPACT_FILTERS", "NETWORK_LIMITED", "P2P_V2"}
+
+// NewNetworkDescriptors creates descriptors for collected network metrics
+func NewNetworkDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_version_info"), "Server version, user agent and protocol version of the node", []string{"chain", "version", "subversion", "protocolversion"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_warnings"), "Current number of active node warnings", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_warning_info"), "Active node warning text", []string{"chain", "warning"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_local_addresses"), "Current number of local addresses advertised by the node", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_local_address_info"), "Local address advertised by the node, with its port and score", []string{"chain", "address", "port", "score"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_onion_local_address"), "Whether the node advertises a Tor onion service address or not. Advertised addresses are removed when the node loses its connection to the Tor control port", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_network_reachable"), "Whether the node can connect to peers on a network or not, e.g. through a proxy for onion", []string{"chain", "network"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_network_limited"), "Whether connections to a network are disabled by -onlynet or not", []string{"chain", "network"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_network_proxy"), "Whether connections to a network are made through a proxy or not", []string{"chain", "network"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_network_proxy_info"), "Proxy address used for connections to a network, and whether random credentials are used for each connection for Tor stream isolation", []string{"chain", "network", "proxy", "randomize_credentials"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_local_service"), "Whether the node advertises a service to its peers or not, e.g. COMPACT_FILTERS with -peerblockfilters", []string{"chain", "service"}, opts.ConstLabels),
+	}
+}
+
+// NewNetworkCollector creates a new prometheus.Collector for getnetworkinfo properties
+func NewNetworkCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &NetworkCollector{client, logger, opts, NewNetworkDescriptors(opts)}
+}
+
+// NetworkCollector builds metrics from getnetworkinfo RPC responses
+type NetworkCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *NetworkCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Warnings decodes the warnings property of getnetworkinfo and getblockchaininfo, which is
+// a single string before v28.0.0 and a list of strings after
+type Warnings []string
+
+// UnmarshalJSON accepts either a string, which is empty when there are no warnings, or a list of strings
+func (warnings *Warnings) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*warnings = nil
+
+		text = strings.TrimSpace(text)
+		if text != "" {
+			*warnings = Warnings{text}
+		}
+
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(warnings))
+}
+
+// GetNetworkInfoResult unmarshals the RPC v24.0.0 getnetworkinfo response message
+type GetNetworkInfoResult struct {
+	btcjson.GetNetworkInfoResult
+
+	LocalServicesNames []string `json:"localservicesnames"`
+	Warnings           Warnings `json:"warnings"`
+}
+
+// Collect calls the getnetworkinfo RPC and builds metrics from its response properties
+func (col *NetworkCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	if !col.Core() {
+		col.collectInfo(out, chain.Chain)
+		return
+	}
+
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetNetworkInfoCmd()))
+	if err != nil {
+		LogRPCError(col.Logger, "getnetworkinfo", err)
+		return
+	}
+
+	var info GetNetworkInfoResult
+	err = col.Decode("getnetworkinfo", data, &info)
+
+	if err != nil {
+		col.Error("Failed to decode getnetworkinfo response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, 1, chain.Chain, strconv.FormatInt(int64(info.Version), 10), info.SubVersion, strconv.FormatInt(int64(info.ProtocolVersion), 10))
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(len(info.Warnings)), chain.Chain)
+	out <- metric
+
+	for _, warning := range info.Warnings {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, 1, chain.Chain, warning)
+		out <- metric
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(len(info.LocalAddresses)), chain.Chain)
+	out <- metric
+
+	onion := 0.0
+	for _, addr := range info.LocalAddresses {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, 1, chain.Chain, addr.Address, strconv.FormatUint(uint64(addr.Port), 10), strconv.FormatInt(int64(addr.Score), 10))
+		out <- metric
+
+		if strings.HasSuffix(addr.Address, ".onion") {
+			onion = 1
+		}
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[5], prometheus.GaugeValue, onion, chain.Chain)
+	out <- metric
+
+	for _, network := range info.Networks {
+		for i, value := range []bool{network.Reachable, network.Limited, network.Proxy != ""} {
+			if value {
+				metric, _ = prometheus.NewConstMetric(col.Descriptors[6+i], prometheus.GaugeValue, 1, chain.Chain, network.Name)
+			} else {
+				metric, _ = prometheus.NewConstMetric(col.Descriptors[6+i], prometheus.GaugeValue, 0, chain.Chain, network.Name)
+			}
+			out <- metric
+		}
+
+		if network.Proxy != "" {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[9], prometheus.GaugeValue, 1, chain.Chain, network.Name, network.Proxy, strconv.FormatBool(network.ProxyRandomizeCredentials))
+			out <- metric
+		}
+	}
+
+	// localservicesnames was added in v0.21.0
+	if !col.Supports(Version0_21) {
+		return
+	}
+
+	services := make(map[string]bool, len(info.LocalServicesNames))
+	for _, name := range info.LocalServicesNames {
+		services[name] = true
+	}
+
+	for _, name := range LocalServices {
+		if services[name] {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[10], prometheus.GaugeValue, 1, chain.Chain, name)
+			delete(services, name)
+		} else {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[10], prometheus.GaugeValue, 0, chain.Chain, name)
+		}
+		out <- metric
+	}
+
+	for name := range services {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[10], prometheus.GaugeValue, 1, chain.Chain, name)
+		out <- metric
+	}
+}
+
+// collectInfo builds version and warning metrics from the getinfo RPC, for btcd nodes that do not implement getnetworkinfo
+func (col *NetworkCollector) collectInfo(out chan<- prometheus.Metric, chain string) {
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetInfoCmd()))
+	if err != nil {
+		LogRPCError(col.Logger, "getinfo", err)
+		return
+	}
+
+	var info struct {
+		btcjson.InfoChainResult
+
+		Errors Warnings `json:"errors"`
+	}
+	err = col.Decode("getinfo", data, &info)
+
+	if err != nil {
+		col.Error("Failed to decode getinfo response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	// getinfo does not report the node's user agent
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, 1, chain, strconv.FormatInt(int64(info.Version), 10), "", strconv.FormatInt(int64(info.ProtocolVersion), 10))
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(len(info.Errors)), chain)
+	out <- metric
+
+	for _, warning := range info.Errors {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, 1, chain, warning)
+		out <- metric
+	}
+}
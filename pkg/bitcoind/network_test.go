@@ -0,0 +1,87 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNetworkCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_version_info", prometheus.Labels{"version": "240000", "subversion": "/Satoshi:24.0.0/"}, 1)
+}
+
+func TestNetworkCollectorWarnings(t *testing.T) {
+	server := bitcoindtest.StartServer(t, 280000)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_warnings", prometheus.Labels{"chain": bitcoindtest.Chain}, 0)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_warning_info", nil)
+
+	info := bitcoindtest.Fixtures(280000)["getnetworkinfo"].(bitcoindtest.Object)
+	info["warnings"] = []string{"This is a pre-release test build"}
+	server.Set("getnetworkinfo", info)
+
+	families = bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_warnings", prometheus.Labels{"chain": bitcoindtest.Chain}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_warning_info", prometheus.Labels{"warning": "This is a pre-release test build"}, 1)
+}
+
+func TestNetworkCollectorLocalAddresses(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_local_addresses", prometheus.Labels{"chain": bitcoindtest.Chain}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_local_address_info", prometheus.Labels{"address": "192.0.2.1", "port": "8333", "score": "4"}, 1)
+}
+
+func TestNetworkCollectorReachable(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_network_reachable", prometheus.Labels{"network": "ipv4"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_network_reachable", prometheus.Labels{"network": "onion"}, 0)
+	bitcoindtest.AssertValue(t, families, "bitcoind_onion_local_address", prometheus.Labels{"chain": bitcoindtest.Chain}, 0)
+}
+
+func TestNetworkCollectorProxy(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_network_proxy", prometheus.Labels{"network": "ipv4"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_network_limited", prometheus.Labels{"network": "ipv4"}, 0)
+}
+
+func TestNetworkCollectorLocalServices(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewNetworkCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_local_service", prometheus.Labels{"service": "WITNESS"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_local_service", prometheus.Labels{"service": "COMPACT_FILTERS"}, 0)
+}
+
+func TestConnectionsCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewConnectionsCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_connections", prometheus.Labels{"chain": bitcoindtest.Chain}, 2)
+}
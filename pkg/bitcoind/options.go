@@ -0,0 +1,364 @@
+package bitcoind
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Peer metric modes
+const (
+	PeersModePeer      = "peer"
+	PeersModeAggregate = "aggregate"
+)
+
+// Fee metric units
+const (
+	FeeUnitBTC = "btc"
+	FeeUnitSat = "sat"
+)
+
+// DefaultNamespace prefixes all bitcoind collector metric names
+const DefaultNamespace = "bitcoind"
+
+// ErrTimeout is returned for RPC calls that do not complete within Options.Timeout
+var ErrTimeout = errors.New("RPC call timed out")
+
+// Logger is the subset of *zap.Logger used by collectors, allowing an embedding application to provide its own logger
+type Logger interface {
+	Debug(msg string, fields ...zap.Field)
+	Info(msg string, fields ...zap.Field)
+	Warn(msg string, fields ...zap.Field)
+	Error(msg string, fields ...zap.Field)
+}
+
+// ChainInfoProvider provides getblockchaininfo results, which every collector uses for its chain label
+type ChainInfoProvider interface {
+	BlockChainInfo() (*GetBlockChainInfoResult, error)
+}
+
+// Stateful is implemented by collectors that keep state between collections, such as counters and cursors.
+// A collector that is rebuilt with new options inherits the state of the collector that it replaces
+type Stateful interface {
+	Inherit(previous prometheus.Collector)
+}
+
+// GetBlockChainInfoResult extends btcjson.GetBlockChainInfoResult with fields added by later node versions
+type GetBlockChainInfoResult struct {
+	btcjson.GetBlockChainInfoResult
+
+	// Time is the best block's timestamp, which is reported from v23.0.0
+	Time int64 `json:"time"`
+}
+
+// Options configures the metrics built by bitcoind collectors
+type Options struct {
+	// Namespace replaces the bitcoind prefix of metric names, if set
+	Namespace string
+
+	// Timeout limits the time waited for each RPC call, if set. A timed out call
+	// is abandoned, but rpcclient may still retry it in the background
+	Timeout time.Duration
+
+	// ChainInfo provides getblockchaininfo results for collectors, which call
+	// getblockchaininfo themselves if it is not set
+	ChainInfo ChainInfoProvider
+
+	// REST reads chain and mempool info from bitcoind's REST interface instead of RPC, if set
+	REST *RESTClient
+
+	// Version is the node's version number from getnetworkinfo. RPCs and response fields added
+	// after Version are not exported, rather than being reported as zero. Nothing is gated if unset
+	Version int32
+
+	// Methods are the RPC methods supported by the node. Optional RPCs that are not supported are skipped.
+	// Every method is assumed to be supported if unset
+	Methods Methods
+
+	// Flavor selects the node implementation from Flavors. Bitcoin Core is assumed if unset
+	Flavor string
+
+	// FeeAsset labels fee metrics from Elements nodes with the asset that fees are paid in. DefaultFeeAsset is used if unset
+	FeeAsset string
+
+	// ConstLabels are added to every metric descriptor
+	ConstLabels prometheus.Labels
+
+	// StrictTypes exports heights as gauges, booleans as gauges, and cumulative
+	// totals as counters. The legacy typing is kept by default for compatibility
+	StrictTypes bool
+
+	// UnitSuffixes exports metric names with OpenMetrics unit and _total
+	// suffixes. Implies StrictTypes so that only counters carry _total
+	UnitSuffixes bool
+
+	// StrictDecoding rejects null RPC responses and responses with values that fail validation, such as
+	// negative sizes, instead of exporting zeros when a new node release changes a response's format
+	StrictDecoding bool
+
+	// FeeUnit selects between BTC and BTC/kvB (FeeUnitBTC, the default) or sat
+	// and sat/vB (FeeUnitSat) for fee amounts and fee rates
+	FeeUnit string
+
+	// PeersMode selects between per-peer and aggregate metrics (PeersModePeer, the
+	// default) or only aggregate metrics (PeersModeAggregate) for connected peers
+	PeersMode string
+
+	// PeerLabels selects the variable labels attached to per-peer metrics from
+	// PeerLabels. All labels are attached if nil
+	PeerLabels []string
+
+	// PeerAddrSalt replaces peer addresses in labels with a hash of the address salted with this value, if set
+	PeerAddrSalt string
+
+	// NoPeerMessages disables per-peer metrics by message type. Totals by message type over all peers are still exported
+	NoPeerMessages bool
+
+	// PingQuantiles overrides the quantiles exported for ping times over all peers, if set
+	PingQuantiles []float64
+
+	// PingHistogram adds a native histogram of ping times over all peers to aggregate peer metrics
+	PingHistogram bool
+
+	// GeoIP adds peer counts by location to aggregate peer metrics, if set
+	GeoIP *GeoIP
+
+	// DataDir is the node's data directory, from which sizes of files such as indexes are read. Metrics built
+	// from files are not exported if unset
+	DataDir string
+
+	// Timestamps attaches the time at which results were collected to metrics from background collectors,
+	// rather than leaving Prometheus to record them at scrape time
+	Timestamps bool
+}
+
+// Option sets a field of Options in collector constructors
+type Option func(*Options)
+
+// NewOptions applies options to a zero Options value
+func NewOptions(options ...Option) Options {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return opts
+}
+
+// WithOptions replaces all fields with those of opts. Options after it override individual fields
+func WithOptions(opts Options) Option {
+	return func(target *Options) {
+		*target = opts
+	}
+}
+
+// WithNamespace replaces the bitcoind prefix of metric names
+func WithNamespace(namespace string) Option {
+	return func(opts *Options) {
+		opts.Namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels to every metric descriptor, in addition to any already set
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(opts *Options) {
+		merged := prometheus.Labels{}
+		for name, value := range opts.ConstLabels {
+			merged[name] = value
+		}
+
+		for name, value := range labels {
+			merged[name] = value
+		}
+
+		opts.ConstLabels = merged
+	}
+}
+
+// WithTimeout limits the time waited for each RPC call
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
+
+// WithChainInfo shares a getblockchaininfo provider between collectors
+func WithChainInfo(provider ChainInfoProvider) Option {
+	return func(opts *Options) {
+		opts.ChainInfo = provider
+	}
+}
+
+// WithREST reads chain and mempool info from bitcoind's REST interface
+func WithREST(rest *RESTClient) Option {
+	return func(opts *Options) {
+		opts.REST = rest
+	}
+}
+
+// Timestamped attaches the time at which a metric's result was collected if Timestamps is set
+func (opts Options) Timestamped(metric prometheus.Metric, collected time.Time) prometheus.Metric {
+	if !opts.Timestamps {
+		return metric
+	}
+
+	return prometheus.NewMetricWithTimestamp(collected, metric)
+}
+
+// Metric returns a metric name with its bitcoind prefix replaced by Namespace, if set
+func (opts Options) Metric(name string) string {
+	if opts.Namespace == "" || opts.Namespace == DefaultNamespace {
+		return name
+	}
+
+	return opts.Namespace + strings.TrimPrefix(name, DefaultNamespace)
+}
+
+// Name returns the suffixed metric name when UnitSuffixes is enabled, or the legacy name otherwise
+func (opts Options) Name(legacy, suffixed string) string {
+	if opts.UnitSuffixes {
+		return opts.Metric(suffixed)
+	}
+
+	return opts.Metric(legacy)
+}
+
+// Receive waits for an RPC response, up to Timeout if set
+func (opts Options) Receive(future chan *rpcclient.Response) ([]byte, error) {
+	if opts.Timeout == 0 {
+		return rpcclient.ReceiveFuture(future)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		data, err := rpcclient.ReceiveFuture(future)
+		done <- result{data, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-time.After(opts.Timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// BlockChainInfo returns getblockchaininfo results from the ChainInfo provider if set, or from client
+func (opts Options) BlockChainInfo(client *rpcclient.Client) (*GetBlockChainInfoResult, error) {
+	if opts.ChainInfo != nil {
+		return opts.ChainInfo.BlockChainInfo()
+	}
+
+	if opts.REST != nil {
+		return opts.REST.BlockChainInfo()
+	}
+
+	data, err := opts.Receive(client.SendCmd(btcjson.NewGetBlockChainInfoCmd()))
+	if err != nil {
+		return nil, err
+	}
+
+	// rpcclient's GetBlockChainInfo decodes getnetworkinfo to select a softforks format, which fails
+	// on v28.0.0 and later. No collector uses softforks, so they are left out
+	var info GetBlockChainInfoResult
+	err = opts.Decode("getblockchaininfo", data, &info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Core() {
+		info.Chain = ChainName(info.Chain)
+	}
+
+	return &info, nil
+}
+
+func (opts Options) strict() bool {
+	return opts.StrictTypes || opts.UnitSuffixes
+}
+
+// HeightType returns the value type for block and header heights, which can decrease on reorgs
+func (opts Options) HeightType() prometheus.ValueType {
+	if opts.strict() {
+		return prometheus.GaugeValue
+	}
+
+	return prometheus.CounterValue
+}
+
+// BoolType returns the value type for 0/1 boolean metrics
+func (opts Options) BoolType() prometheus.ValueType {
+	if opts.strict() {
+		return prometheus.GaugeValue
+	}
+
+	return prometheus.UntypedValue
+}
+
+// TotalType returns the value type for cumulative totals that were exported as gauges
+func (opts Options) TotalType() prometheus.ValueType {
+	if opts.strict() {
+		return prometheus.CounterValue
+	}
+
+	return prometheus.GaugeValue
+}
+
+// AmountUnit describes the unit of fee amounts for metric help text
+func (opts Options) AmountUnit() string {
+	if opts.FeeUnit == FeeUnitSat {
+		return "sat"
+	}
+
+	return "BTC"
+}
+
+// Amount converts a BTC amount from an RPC response to the configured fee unit
+func (opts Options) Amount(btc float64) float64 {
+	if opts.FeeUnit == FeeUnitSat {
+		return math.Round(btc * 1e8)
+	}
+
+	return btc
+}
+
+// FeeRateUnit describes the unit of fee rates for metric help text
+func (opts Options) FeeRateUnit() string {
+	if opts.FeeUnit == FeeUnitSat {
+		return "sat/vB"
+	}
+
+	return "BTC/kvB"
+}
+
+// FeeRate converts a BTC/kvB fee rate from an RPC response to the configured fee unit
+func (opts Options) FeeRate(btcPerKvB float64) float64 {
+	if opts.FeeUnit == FeeUnitSat {
+		// Round to whole sat/kvB, which is the precision that bitcoind works in
+		return math.Round(btcPerKvB*1e8) / 1e3
+	}
+
+	return btcPerKvB
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package bitcoind_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestMetricTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		options bitcoind.Options
+		types   []string
+	}{
+		{
+			name:    "legacy",
+			options: bitcoind.Options{},
+			types: []string{
+				"# TYPE bitcoind_blockchain_blocks counter",
+				"# TYPE bitcoind_blockchain_headers counter",
+				"# TYPE bitcoind_initial_block_download untyped",
+				"# TYPE bitcoind_peer_bytes_sent gauge",
+				"# TYPE bitcoind_peer_bytes_recv gauge",
+			},
+		},
+		{
+			name:    "strict",
+			options: bitcoind.Options{StrictTypes: true},
+			types: []string{
+				"# TYPE bitcoind_blockchain_blocks gauge",
+				"# TYPE bitcoind_blockchain_headers gauge",
+				"# TYPE bitcoind_initial_block_download gauge",
+				"# TYPE bitcoind_peer_bytes_sent counter",
+				"# TYPE bitcoind_peer_bytes_recv counter",
+			},
+		},
+		{
+			name:    "unit suffixes",
+			options: bitcoind.Options{UnitSuffixes: true},
+			types: []string{
+				"# TYPE bitcoind_blockchain_blocks gauge",
+				"# TYPE bitcoind_initial_block_download gauge",
+				"# TYPE bitcoind_peer_sent_bytes_total counter",
+				"# TYPE bitcoind_peer_recv_bytes_total counter",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := bitcoindtest.StartServer(t, bitcoind.Version24)
+			client := server.Client(t)
+			logger := bitcoindtest.Logger(t)
+
+			families := bitcoindtest.Gather(t,
+				bitcoind.NewBlockchainCollector(client, logger, bitcoind.WithOptions(test.options)),
+				bitcoind.NewPeersCollector(client, logger, bitcoind.WithOptions(test.options)),
+			)
+
+			var text bytes.Buffer
+			for _, family := range families {
+				_, err := expfmt.MetricFamilyToText(&text, family)
+				if err != nil {
+					t.Fatalf("unable to encode %s: %v", family.GetName(), err)
+				}
+			}
+
+			lines := strings.Split(text.String(), "\n")
+			for _, expected := range test.types {
+				if !contains(lines, expected) {
+					t.Errorf("exposition has no line %q", expected)
+				}
+			}
+		})
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, candidate := range lines {
+		if candidate == line {
+			return true
+		}
+	}
+
+	return false
+}
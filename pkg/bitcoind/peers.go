@@ -1,8 +1,13 @@
 package bitcoind
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/rpcclient"
@@ -10,50 +15,170 @@ import (
 	"go.uber.org/zap"
 )
 
-// PeersDescriptors contains cached descriptor values for collected peer metrics
-var PeersDescriptors = []*prometheus.Desc{
-	prometheus.NewDesc("bitcoind_peer_last_send", "UNIX epoch time of the last message sent to the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_last_recv", "UNIX epoch time of the last message received from the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_last_transaction", "UNIX epoch time of the last valid transaction received from the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_last_block", "UNIX epoch time of the last block received from the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_bytes_sent", "Total bytes sent to the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_bytes_recv", "Total bytes received from the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_time_offset", "Time offset in seconds from the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_ping_time", "Ping time to the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_ping_min", "Minimum observed ping time to the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_starting_height", "Starting height (block) of the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_presynced_headers", "Current height of header pre-synchronization with this peer, or -1 if no low-work sync is in progress", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_synced_headers", "Last header we have in common with the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_synced_blocks", "Last block we have in common with the peer", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_addr_processed", "Total number of addresses processed, excluding those dropped due to rate limiting", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_addr_rate_limited", "Total number number of addresses dropped due to rate limiting", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_bytes_sent_per_msg", "Total bytes sent to the peer aggregated by message type", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version", "msg_type"}, prometheus.Labels{}),
-	prometheus.NewDesc("bitcoind_peer_bytes_recv_per_msg", "Total bytes received from the peer aggregated by message type", []string{"chain", "peer_id", "peer_addr", "peer_transport", "peer_version", "msg_type"}, prometheus.Labels{}),
+// PeerLabels lists the variable labels that can be attached to per-peer metrics, in the order that they are exported
+var PeerLabels = []string{"peer_id", "peer_addr", "peer_transport", "peer_version", "peer_connection_type", "peer_inbound", "peer_asn", "peer_permissions"}
+
+// ConnectionTypes lists the connection types reported by getpeerinfo since v0.21.0, which are always exported in
+// peer counts
+var ConnectionTypes = []string{"outbound-full-relay", "block-relay-only", "inbound", "manual", "feeler", "addr-fetch"}
+
+// PeerPermissions lists the -whitelist and -whitebind permission flags, which are always exported in peer counts
+var PeerPermissions = []string{"noban", "bloomfilter", "forcerelay", "relay", "mempool", "download", "addr"}
+
+// PeerLabelNames returns the variable label names for per-peer metrics
+func (opts Options) PeerLabelNames() []string {
+	names := []string{"chain"}
+
+	for _, name := range PeerLabels {
+		if opts.HasPeerLabel(name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// HasPeerLabel checks if a label is enabled for per-peer metrics. All labels are enabled if PeerLabels is nil
+func (opts Options) HasPeerLabel(name string) bool {
+	return opts.PeerLabels == nil || contains(opts.PeerLabels, name)
+}
+
+// IsPeerLabel checks if a name is one of PeerLabels
+func IsPeerLabel(name string) bool {
+	return contains(PeerLabels, name)
+}
+
+// PeerAddr returns the peer address label value, replaced by a salted hash if PeerAddrSalt is set
+func (opts Options) PeerAddr(addr string) string {
+	if opts.PeerAddrSalt == "" {
+		return addr
+	}
+
+	sum := sha256.Sum256([]byte(opts.PeerAddrSalt + addr))
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewPeersDescriptors creates descriptors for collected peer metrics
+func NewPeersDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Name("bitcoind_peer_last_send", "bitcoind_peer_last_send_timestamp_seconds"), "UNIX epoch time of the last message sent to the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_last_recv", "bitcoind_peer_last_recv_timestamp_seconds"), "UNIX epoch time of the last message received from the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_last_transaction", "bitcoind_peer_last_transaction_timestamp_seconds"), "UNIX epoch time of the last valid transaction received from the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_last_block", "bitcoind_peer_last_block_timestamp_seconds"), "UNIX epoch time of the last block received from the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_bytes_sent", "bitcoind_peer_sent_bytes_total"), "Total bytes sent to the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_bytes_recv", "bitcoind_peer_recv_bytes_total"), "Total bytes received from the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_time_offset", "bitcoind_peer_time_offset_seconds"), "Time offset in seconds from the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_ping_time", "bitcoind_peer_ping_time_seconds"), "Ping time to the peer in seconds", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_ping_min", "bitcoind_peer_ping_min_seconds"), "Minimum observed ping time to the peer in seconds", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_starting_height"), "Starting height (block) of the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_presynced_headers"), "Current height of header pre-synchronization with this peer, or -1 if no low-work sync is in progress", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_synced_headers"), "Last header we have in common with the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_synced_blocks"), "Last block we have in common with the peer", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_addr_processed", "bitcoind_peer_addr_processed_total"), "Total number of addresses processed, excluding those dropped due to rate limiting", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_addr_rate_limited", "bitcoind_peer_addr_rate_limited_total"), "Total number number of addresses dropped due to rate limiting", opts.PeerLabelNames(), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_bytes_sent_per_msg", "bitcoind_peer_msg_sent_bytes_total"), "Total bytes sent to the peer aggregated by message type", append(opts.PeerLabelNames(), "msg_type"), opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peer_bytes_recv_per_msg", "bitcoind_peer_msg_recv_bytes_total"), "Total bytes received from the peer aggregated by message type", append(opts.PeerLabelNames(), "msg_type"), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_min_fee_filter"), "Minimum fee rate in "+opts.FeeRateUnit()+" for transactions announced to the peer, from its BIP 133 feefilter", opts.PeerLabelNames(), opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_peer_ban_score"), "Misbehavior score of the peer, reported by Bitcoin Knots", opts.PeerLabelNames(), opts.ConstLabels),
+	}
+}
+
+// NewPeersAggregateDescriptors creates descriptors for metrics aggregated over all peers
+func NewPeersAggregateDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_peers"), "Current number of connected peers by network and direction", []string{"chain", "network", "direction"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_subversion"), "Current number of connected peers by user agent", []string{"chain", "subversion"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peers_bytes_sent", "bitcoind_peers_sent_bytes"), "Sum of bytes sent to currently connected peers", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peers_bytes_recv", "bitcoind_peers_recv_bytes"), "Sum of bytes received from currently connected peers", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peers_ping_time", "bitcoind_peers_ping_time_seconds"), "Quantiles of ping time in seconds over currently connected peers", []string{"chain", "quantile"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_country"), "Current number of connected peers by country and continent, when a GeoIP database is configured", []string{"chain", "country", "continent"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_asn"), "Current number of connected peers by autonomous system, when the node is configured with an asmap", []string{"chain", "asn"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_min_fee_filter_median"), "Median of BIP 133 feefilter fee rates in "+opts.FeeRateUnit()+" over currently connected peers", []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_bip152_high_bandwidth"), "Current number of BIP 152 high-bandwidth compact block relationships. Selected is \"to\" for peers we selected, and \"from\" for peers that selected us", []string{"chain", "selected"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peers_bytes_sent_per_msg", "bitcoind_peers_msg_sent_bytes"), "Sum of bytes sent to currently connected peers by message type", []string{"chain", "msg_type"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Name("bitcoind_peers_bytes_recv_per_msg", "bitcoind_peers_msg_recv_bytes"), "Sum of bytes received from currently connected peers by message type", []string{"chain", "msg_type"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_max_abs_time_offset_seconds"), "Largest absolute clock offset in seconds reported by a currently connected peer", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_median_time_offset_seconds"), "Median clock offset in seconds over currently connected peers", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_no_relay"), "Current number of connected peers that do not relay transactions (relay transactions), such as blocks-only connections, or to which the node does not relay addresses (relay addresses)", []string{"chain", "relay"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_permission"), "Current number of connected peers granted a permission flag by -whitelist or -whitebind", []string{"chain", "permission"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_peers_connection_type"), "Current number of connected peers by connection type. Nodes before v0.21.0 only report inbound, manual and outbound connections", []string{"chain", "connection_type"}, opts.ConstLabels),
+	}
 }
 
 // NewPeersCollector creates a new prometheus.Collector for getpeerinfo properties
-func NewPeersCollector(client *rpcclient.Client, logger *zap.Logger) prometheus.Collector {
-	return &PeersCollector{client, logger}
+func NewPeersCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &PeersCollector{client, logger, opts, NewPeersDescriptors(opts), NewPeersAggregateDescriptors(opts)}
 }
 
 // PeersCollector builds metrics from getpeerinfo RPC responses
 type PeersCollector struct {
 	*rpcclient.Client
-	*zap.Logger
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+	Aggregates  []*prometheus.Desc
 }
 
 // Describe returns the collector's metric descriptor set
 func (col *PeersCollector) Describe(out chan<- *prometheus.Desc) {
-	for _, desc := range PeersDescriptors {
+	for _, desc := range col.Descriptors {
 		out <- desc
 	}
+
+	for _, desc := range col.Aggregates {
+		out <- desc
+	}
+
+	if col.PingHistogram {
+		NewPingHistogramVec(col.Options).Describe(out)
+	}
+}
+
+// PingQuantiles are exported for ping times over all peers unless Options.PingQuantiles is set
+var PingQuantiles = []float64{0.5, 0.9, 0.99}
+
+// PeerPingQuantiles returns the quantiles to export for ping times over all peers
+func (opts Options) PeerPingQuantiles() []float64 {
+	if opts.PingQuantiles == nil {
+		return PingQuantiles
+	}
+
+	return opts.PingQuantiles
 }
 
+// PingBuckets are classic histogram bucket bounds for ping times, exposed alongside native histogram
+// buckets to scrapers that do not negotiate the protobuf exposition format
+var PingBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PingHistogramBucketFactor bounds the growth between native histogram buckets for ping times
+var PingHistogramBucketFactor = 1.1
+
+// NewPingHistogramVec creates a histogram vector for ping times over all peers. A new vector is
+// created for each collection so that the histogram only describes currently connected peers
+func NewPingHistogramVec(opts Options) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                        opts.Metric("bitcoind_peers_ping_duration_seconds"),
+		Help:                        "Distribution of ping times in seconds over currently connected peers",
+		ConstLabels:                 opts.ConstLabels,
+		Buckets:                     PingBuckets,
+		NativeHistogramBucketFactor: PingHistogramBucketFactor,
+	}, []string{"chain"})
+}
+
+// PeerNetworks lists the networks reported by getpeerinfo, which are always exported in peer counts
+var PeerNetworks = []string{"ipv4", "ipv6", "onion", "i2p", "cjdns", "not_publicly_routable"}
+
 // GetPeerInfoResult extends btcjson.GetPeerInfoResult with more fields for RPC v24.0.0
 type GetPeerInfoResult struct {
 	btcjson.GetPeerInfoResult
 
-	Network string `json:"network"`
+	Network        string `json:"network"`
+	ConnectionType string `json:"connection_type"`
+	AddNode        bool   `json:"addnode"`
+	MappedAS       uint32 `json:"mapped_as"`
 
 	LastTransaction int64 `json:"last_transaction"`
 	LastBlock       int64 `json:"last_block"`
@@ -64,92 +189,443 @@ type GetPeerInfoResult struct {
 	SyncedHeaders    int64 `json:"synced_headers"`
 	SyncedBlocks     int64 `json:"synced_blocks"`
 
-	AddrProcessed   int64 `json:"addr_processed"`
-	AddrRateLimited int64 `json:"addr_rate_limited"`
+	HighBandwidthTo   bool `json:"bip152_hb_to"`
+	HighBandwidthFrom bool `json:"bip152_hb_from"`
+
+	AddrProcessed    int64 `json:"addr_processed"`
+	AddrRateLimited  int64 `json:"addr_rate_limited"`
+	AddrRelayEnabled bool  `json:"addr_relay_enabled"`
+
+	Permissions []string `json:"permissions"`
 
 	MinFeeFilter float64 `json:"minfeefilter"`
 
+	// BanScore is reported by Bitcoin Knots, and is nil if it is not reported
+	BanScore *int32 `json:"banscore"`
+
 	BytesRecvPerMessage map[string]int64 `json:"bytesrecv_per_msg"`
 	BytesSentPerMessage map[string]int64 `json:"bytessent_per_msg"`
 }
 
+// GetPeerInfoResults decodes the getpeerinfo response, a list of connected peers
+type GetPeerInfoResults []GetPeerInfoResult
+
+// Validate checks that every peer has a unique id and an address, and that ping times, fee filters and
+// message byte counts are not negative
+func (peers GetPeerInfoResults) Validate() error {
+	ids := map[int32]bool{}
+
+	for _, peer := range peers {
+		if ids[peer.ID] {
+			return &ValidationError{"id", fmt.Sprintf("duplicate peer id %d", peer.ID)}
+		}
+
+		ids[peer.ID] = true
+
+		if peer.Addr == "" {
+			return &ValidationError{"addr", fmt.Sprintf("missing address of peer %d", peer.ID)}
+		}
+
+		for field, value := range map[string]float64{"pingtime": peer.PingTime, "minping": peer.PingMin, "minfeefilter": peer.MinFeeFilter} {
+			if err := validateAmount(field, value); err != nil {
+				return err
+			}
+		}
+
+		for msg, value := range peer.BytesRecvPerMessage {
+			if err := validateCount("bytesrecv_per_msg."+msg, value); err != nil {
+				return err
+			}
+		}
+
+		for msg, value := range peer.BytesSentPerMessage {
+			if err := validateCount("bytessent_per_msg."+msg, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// PermissionList returns the peer's permission flags, sorted and separated by commas
+func (peer GetPeerInfoResult) PermissionList() string {
+	permissions := append([]string(nil), peer.Permissions...)
+	sort.Strings(permissions)
+
+	return strings.Join(permissions, ",")
+}
+
+// ASN returns the peer's autonomous system number from the node's asmap, or an empty string if the node has no asmap
+func (peer GetPeerInfoResult) ASN() string {
+	if peer.MappedAS == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(peer.MappedAS), 10)
+}
+
+// Connection returns the peer's connection type. Nodes before v0.21.0 only report addnode, from which inbound,
+// manual and outbound connections can be told apart
+func (peer GetPeerInfoResult) Connection() string {
+	switch {
+	case peer.ConnectionType != "":
+		return peer.ConnectionType
+	case peer.Inbound:
+		return "inbound"
+	case peer.AddNode:
+		return "manual"
+	default:
+		return "outbound"
+	}
+}
+
 // Collect calls the getpeerinfo RPC and builds metrics from its response properties
 func (col *PeersCollector) Collect(out chan<- prometheus.Metric) {
-	chain, err := col.GetBlockChainInfo()
+	chain, err := col.BlockChainInfo(col.Client)
 	if err != nil {
-		col.Error("RPC call getblockchaininfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getblockchaininfo", err)
 		return
 	}
 
-	data, err := rpcclient.ReceiveFuture(col.SendCmd(&btcjson.GetPeerInfoCmd{}))
+	data, err := col.Receive(col.SendCmd(&btcjson.GetPeerInfoCmd{}))
 	if err != nil {
-		col.Error("RPC call getpeerinfo failed", zap.Error(err))
+		LogRPCError(col.Logger, "getpeerinfo", err)
 		return
 	}
 
-	var info []GetPeerInfoResult
-	err = json.Unmarshal(data, &info)
+	var info GetPeerInfoResults
+	err = col.Decode("getpeerinfo", data, &info)
 
 	if err != nil {
 		col.Error("Failed to decode getpeerinfo response", zap.Error(err))
 		return
 	}
 
-	for _, peer := range info {
-		peerID := strconv.FormatInt(int64(peer.ID), 16)
+	if !col.Core() {
+		for i, peer := range info {
+			info[i] = peer.FromBtcd()
+		}
+	}
 
-		metric, _ := prometheus.NewConstMetric(PeersDescriptors[0], prometheus.GaugeValue, float64(peer.LastSend), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
-		out <- metric
+	LastCollection.Mark()
+
+	if col.PeersMode != PeersModeAggregate {
+		for _, peer := range info {
+			col.collectPeer(out, chain.Chain, peer)
+		}
+	}
+
+	col.collectAggregates(out, chain.Chain, info)
+}
+
+// peerLabelValues returns the values of enabled per-peer labels
+func (col *PeersCollector) peerLabelValues(chain string, peer GetPeerInfoResult) []string {
+	values := []string{chain}
+
+	for _, name := range PeerLabels {
+		if !col.HasPeerLabel(name) {
+			continue
+		}
+
+		switch name {
+		case "peer_id":
+			values = append(values, strconv.FormatInt(int64(peer.ID), 16))
+		case "peer_addr":
+			values = append(values, col.PeerAddr(peer.Addr))
+		case "peer_transport":
+			values = append(values, peer.Network)
+		case "peer_version":
+			values = append(values, peer.SubVer)
+		case "peer_connection_type":
+			values = append(values, peer.Connection())
+		case "peer_inbound":
+			values = append(values, strconv.FormatBool(peer.Inbound))
+		case "peer_asn":
+			values = append(values, peer.ASN())
+		case "peer_permissions":
+			values = append(values, peer.PermissionList())
+		}
+	}
+
+	return values
+}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[1], prometheus.GaugeValue, float64(peer.LastRecv), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+// collectPeer builds per-peer metrics
+func (col *PeersCollector) collectPeer(out chan<- prometheus.Metric, chain string, peer GetPeerInfoResult) {
+	labels := col.peerLabelValues(chain, peer)
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(peer.LastSend), labels...)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(peer.LastRecv), labels...)
+	out <- metric
+
+	// last_transaction and last_block were added in v0.21.0
+	if col.Supports(Version0_21) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(peer.LastTransaction), labels...)
 		out <- metric
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[2], prometheus.GaugeValue, float64(peer.LastTransaction), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(peer.LastBlock), labels...)
 		out <- metric
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[4], col.TotalType(), float64(peer.BytesSent), labels...)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[5], col.TotalType(), float64(peer.BytesRecv), labels...)
+	out <- metric
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[3], prometheus.GaugeValue, float64(peer.LastBlock), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[6], prometheus.GaugeValue, float64(peer.TimeOffset), labels...)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[7], prometheus.GaugeValue, float64(peer.PingTime), labels...)
+	out <- metric
+
+	// minping was added in v0.19.0
+	if col.Supports(Version0_19) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[8], prometheus.GaugeValue, float64(peer.PingMin), labels...)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[4], prometheus.GaugeValue, float64(peer.BytesSent), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[9], prometheus.GaugeValue, float64(peer.StartingHeight), labels...)
+	out <- metric
+
+	// presynced_headers was added in v24.0.0
+	if col.Supports(Version24) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[10], col.HeightType(), float64(peer.PreSyncedHeaders), labels...)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[5], prometheus.GaugeValue, float64(peer.BytesRecv), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	// btcd does not track headers and blocks in common with peers
+	if col.Core() {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[11], col.HeightType(), float64(peer.SyncedHeaders), labels...)
 		out <- metric
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[6], prometheus.GaugeValue, float64(peer.TimeOffset), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[12], col.HeightType(), float64(peer.SyncedBlocks), labels...)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[7], prometheus.GaugeValue, float64(peer.PingTime), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	// addr_processed and addr_rate_limited were added in v23.0.0
+	if col.Supports(Version23) {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[13], prometheus.CounterValue, float64(peer.AddrProcessed), labels...)
 		out <- metric
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[8], prometheus.GaugeValue, float64(peer.PingMin), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[14], prometheus.CounterValue, float64(peer.AddrRateLimited), labels...)
 		out <- metric
+	}
+
+	if !col.NoPeerMessages {
+		for msg, count := range peer.BytesSentPerMessage {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[15], prometheus.CounterValue, float64(count), append(labels[:len(labels):len(labels)], msg)...)
+			out <- metric
+		}
+
+		for msg, count := range peer.BytesRecvPerMessage {
+			metric, _ = prometheus.NewConstMetric(col.Descriptors[16], prometheus.CounterValue, float64(count), append(labels[:len(labels):len(labels)], msg)...)
+			out <- metric
+		}
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[17], prometheus.GaugeValue, col.FeeRate(peer.MinFeeFilter), labels...)
+	out <- metric
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[9], prometheus.GaugeValue, float64(peer.StartingHeight), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	if col.Knots() && peer.BanScore != nil {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[18], prometheus.GaugeValue, float64(*peer.BanScore), labels...)
 		out <- metric
+	}
+}
+
+// collectAggregates builds metrics aggregated over all peers
+func (col *PeersCollector) collectAggregates(out chan<- prometheus.Metric, chain string, info []GetPeerInfoResult) {
+	counts := map[string]map[string]int{}
+	for _, network := range PeerNetworks {
+		counts[network] = map[string]int{"inbound": 0, "outbound": 0}
+	}
+
+	subversions := map[string]int{}
+	permissions := map[string]int{}
+	for _, permission := range PeerPermissions {
+		permissions[permission] = 0
+	}
+
+	connections := map[string]int{}
+	if col.Supports(Version0_21) {
+		for _, connection := range ConnectionTypes {
+			connections[connection] = 0
+		}
+	}
+
+	asns := map[string]int{}
+	locations := map[[2]string]int{}
+	sentPerMessage := map[string]int64{}
+	recvPerMessage := map[string]int64{}
+
+	var sent, recv uint64
+	var hbTo, hbFrom, noTxRelay, noAddrRelay int
+	var pings, feeFilters, offsets []float64
+	var maxOffset float64
+
+	for _, peer := range info {
+		subversions[peer.SubVer]++
+
+		if asn := peer.ASN(); asn != "" {
+			asns[asn]++
+		}
+
+		if col.GeoIP != nil {
+			country, continent := col.GeoIP.Locate(peer.Addr)
+			locations[[2]string{country, continent}]++
+		}
+
+		sent += peer.BytesSent
+		recv += peer.BytesRecv
+		feeFilters = append(feeFilters, col.FeeRate(peer.MinFeeFilter))
+		offsets = append(offsets, float64(peer.TimeOffset))
+		maxOffset = math.Max(maxOffset, math.Abs(float64(peer.TimeOffset)))
+
+		for msg, count := range peer.BytesSentPerMessage {
+			sentPerMessage[msg] += count
+		}
+
+		for msg, count := range peer.BytesRecvPerMessage {
+			recvPerMessage[msg] += count
+		}
+
+		if peer.HighBandwidthTo {
+			hbTo++
+		}
+
+		if peer.HighBandwidthFrom {
+			hbFrom++
+		}
+
+		if !peer.RelayTxes {
+			noTxRelay++
+		}
+
+		for _, permission := range peer.Permissions {
+			permissions[permission]++
+		}
+
+		connections[peer.Connection()]++
+
+		if !peer.AddrRelayEnabled {
+			noAddrRelay++
+		}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[10], prometheus.CounterValue, float64(peer.PreSyncedHeaders), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+		// Peers that have not responded to a ping yet report zero
+		if peer.PingTime > 0 {
+			pings = append(pings, peer.PingTime)
+		}
+
+		if _, has := counts[peer.Network]; !has {
+			counts[peer.Network] = map[string]int{"inbound": 0, "outbound": 0}
+		}
+
+		if peer.Inbound {
+			counts[peer.Network]["inbound"]++
+		} else {
+			counts[peer.Network]["outbound"]++
+		}
+	}
+
+	for network, directions := range counts {
+		for direction, count := range directions {
+			metric, _ := prometheus.NewConstMetric(col.Aggregates[0], prometheus.GaugeValue, float64(count), chain, network, direction)
+			out <- metric
+		}
+	}
+
+	for subversion, count := range subversions {
+		metric, _ := prometheus.NewConstMetric(col.Aggregates[1], prometheus.GaugeValue, float64(count), chain, subversion)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[11], prometheus.CounterValue, float64(peer.SyncedHeaders), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	for location, count := range locations {
+		metric, _ := prometheus.NewConstMetric(col.Aggregates[5], prometheus.GaugeValue, float64(count), chain, location[0], location[1])
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[12], prometheus.CounterValue, float64(peer.SyncedBlocks), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	for asn, count := range asns {
+		metric, _ := prometheus.NewConstMetric(col.Aggregates[6], prometheus.GaugeValue, float64(count), chain, asn)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[13], prometheus.CounterValue, float64(peer.AddrProcessed), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	for msg, count := range sentPerMessage {
+		metric, _ := prometheus.NewConstMetric(col.Aggregates[9], prometheus.GaugeValue, float64(count), chain, msg)
 		out <- metric
+	}
 
-		metric, _ = prometheus.NewConstMetric(PeersDescriptors[14], prometheus.CounterValue, float64(peer.AddrRateLimited), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer)
+	for msg, count := range recvPerMessage {
+		metric, _ := prometheus.NewConstMetric(col.Aggregates[10], prometheus.GaugeValue, float64(count), chain, msg)
 		out <- metric
+	}
 
-		for msg, count := range peer.BytesSentPerMessage {
-			metric, _ = prometheus.NewConstMetric(PeersDescriptors[15], prometheus.CounterValue, float64(count), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer, msg)
+	metric, _ := prometheus.NewConstMetric(col.Aggregates[2], prometheus.GaugeValue, float64(sent), chain)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Aggregates[3], prometheus.GaugeValue, float64(recv), chain)
+	out <- metric
+
+	if len(pings) > 0 {
+		for _, q := range col.PeerPingQuantiles() {
+			metric, _ = prometheus.NewConstMetric(col.Aggregates[4], prometheus.GaugeValue, Quantile(pings, q), chain, strconv.FormatFloat(q, 'f', -1, 64))
 			out <- metric
 		}
+	}
 
-		for msg, count := range peer.BytesRecvPerMessage {
-			metric, _ = prometheus.NewConstMetric(PeersDescriptors[16], prometheus.CounterValue, float64(count), chain.Chain, peerID, peer.Addr, peer.Network, peer.SubVer, msg)
+	if col.PingHistogram {
+		hists := NewPingHistogramVec(col.Options)
+		hist := hists.WithLabelValues(chain)
+
+		for _, ping := range pings {
+			hist.Observe(ping)
+		}
+
+		hists.Collect(out)
+	}
+
+	if len(feeFilters) > 0 {
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[7], prometheus.GaugeValue, Quantile(feeFilters, 0.5), chain)
+		out <- metric
+	}
+
+	if len(offsets) > 0 {
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[11], prometheus.GaugeValue, maxOffset, chain)
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[12], prometheus.GaugeValue, Quantile(offsets, 0.5), chain)
+		out <- metric
+	}
+
+	// bip152_hb_to and bip152_hb_from were added in v22.0.0
+	if col.Supports(Version22) {
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[8], prometheus.GaugeValue, float64(hbTo), chain, "to")
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[8], prometheus.GaugeValue, float64(hbFrom), chain, "from")
+		out <- metric
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Aggregates[13], prometheus.GaugeValue, float64(noTxRelay), chain, "transactions")
+	out <- metric
+
+	// addr_relay_enabled was added in v23.0.0
+	if col.Supports(Version23) {
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[13], prometheus.GaugeValue, float64(noAddrRelay), chain, "addresses")
+		out <- metric
+	}
+
+	for connection, count := range connections {
+		metric, _ = prometheus.NewConstMetric(col.Aggregates[15], prometheus.GaugeValue, float64(count), chain, connection)
+		out <- metric
+	}
+
+	// btcd does not report permissions
+	if col.Core() {
+		for permission, count := range permissions {
+			metric, _ = prometheus.NewConstMetric(col.Aggregates[14], prometheus.GaugeValue, float64(count), chain, permission)
 			out <- metric
 		}
 	}
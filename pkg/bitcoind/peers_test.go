@@ -0,0 +1,83 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPeersCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	outbound := prometheus.Labels{"peer_id": "1", "peer_addr": "203.0.113.1:8333"}
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent", outbound, 1000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_recv", outbound, 2000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_ping_time", outbound, 0.05)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_synced_headers", outbound, bitcoindtest.Height)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent_per_msg", prometheus.Labels{"peer_id": "1", "msg_type": "inv"}, 680)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers", prometheus.Labels{"network": "ipv4", "direction": "inbound"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers", prometheus.Labels{"network": "ipv4", "direction": "outbound"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers", prometheus.Labels{"network": "onion", "direction": "inbound"}, 0)
+}
+
+func TestPeersCollectorAggregate(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithOptions(bitcoind.Options{PeersMode: bitcoind.PeersModeAggregate}))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_bytes_sent", chain, 1500)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_bytes_recv", chain, 2700)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_peer_bytes_sent", nil)
+}
+
+func TestPeersCollectorConnectionLabels(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent", prometheus.Labels{"peer_id": "1", "peer_connection_type": "outbound-full-relay", "peer_inbound": "false"}, 1000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent", prometheus.Labels{"peer_id": "2", "peer_connection_type": "inbound", "peer_inbound": "true"}, 500)
+}
+
+func TestPeersCollectorTimeOffset(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	// Peers report offsets of -1 and 3 seconds
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_max_abs_time_offset_seconds", chain, 3)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_median_time_offset_seconds", chain, 1)
+}
+
+func TestPeersCollectorPermissions(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_permission", prometheus.Labels{"permission": "noban"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peers_permission", prometheus.Labels{"permission": "mempool"}, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent", prometheus.Labels{"peer_id": "2", "peer_permissions": "mempool,noban"}, 500)
+}
+
+func TestPeersCollectorOldVersion(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version0_19)
+
+	col := bitcoind.NewPeersCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithVersion(bitcoind.Version0_19))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_peer_bytes_sent", prometheus.Labels{"peer_id": "1"}, 1000)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_peer_presynced_headers", nil)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_peer_addr_processed", nil)
+}
@@ -0,0 +1,136 @@
+package bitcoind
+
+// /proc/<pid>
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"go.uber.org/zap"
+)
+
+// NewProcessDescriptors creates descriptors for collected bitcoind process metrics
+func NewProcessDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_process_cpu_seconds_total"), "Total user and system CPU time spent by the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_resident_memory_bytes"), "Resident memory size of the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_virtual_memory_bytes"), "Virtual memory size of the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_open_fds"), "Number of open file descriptors held by the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_max_fds"), "Maximum number of open file descriptors for the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_threads"), "Number of threads in the bitcoind process", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_process_start_time_seconds"), "Start time of the bitcoind process since UNIX epoch", nil, opts.ConstLabels),
+	}
+}
+
+// NewProcessCollector creates a new prometheus.Collector for a local bitcoind process. The process
+// is found by the PID in pidfile if set, or otherwise by its command name
+func NewProcessCollector(logger Logger, pidfile, name string, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &ProcessCollector{logger, opts, pidfile, name, NewProcessDescriptors(opts)}
+}
+
+// ProcessCollector builds metrics from /proc for a bitcoind process running on the same host
+type ProcessCollector struct {
+	Logger
+	Options
+
+	PIDFile string
+	Name    string
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ProcessCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// PID resolves the bitcoind process' ID on each call, as it changes when bitcoind is restarted
+func (col *ProcessCollector) PID(fs procfs.FS) (int, error) {
+	if col.PIDFile != "" {
+		data, err := os.ReadFile(col.PIDFile)
+		if err != nil {
+			return 0, err
+		}
+
+		return strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, proc := range procs {
+		comm, err := proc.Comm()
+		if err == nil && comm == col.Name {
+			return proc.PID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no process named %q", col.Name)
+}
+
+// Collect reads the bitcoind process' stat and limits from /proc
+func (col *ProcessCollector) Collect(out chan<- prometheus.Metric) {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		col.Error("Unable to open procfs", zap.Error(err))
+		return
+	}
+
+	pid, err := col.PID(fs)
+	if err != nil {
+		col.Error("Unable to find bitcoind process", zap.Error(err))
+		return
+	}
+
+	proc, err := fs.Proc(pid)
+	if err != nil {
+		col.Error("Unable to read bitcoind process", zap.Int("pid", pid), zap.Error(err))
+		return
+	}
+
+	stat, err := proc.Stat()
+	if err != nil {
+		col.Error("Unable to read bitcoind process stat", zap.Int("pid", pid), zap.Error(err))
+		return
+	}
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.CounterValue, stat.CPUTime())
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(stat.ResidentMemory()))
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(stat.VirtualMemory()))
+	out <- metric
+
+	// Reading another user's fd directory requires privileges that the exporter may not have
+	if fds, err := proc.FileDescriptorsLen(); err == nil {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, float64(fds))
+		out <- metric
+	} else {
+		col.Debug("Unable to read bitcoind process file descriptors", zap.Int("pid", pid), zap.Error(err))
+	}
+
+	if limits, err := proc.Limits(); err == nil {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[4], prometheus.GaugeValue, float64(limits.OpenFiles))
+		out <- metric
+	}
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[5], prometheus.GaugeValue, float64(stat.NumThreads))
+	out <- metric
+
+	if start, err := stat.StartTime(); err == nil {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[6], prometheus.GaugeValue, start)
+		out <- metric
+	}
+}
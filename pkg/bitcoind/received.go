@@ -0,0 +1,186 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// GetReceivedByLabelCmd calls the getreceivedbylabel RPC
+type GetReceivedByLabelCmd struct {
+	Label   string
+	MinConf *int `jsonrpcdefault:"1"`
+}
+
+func init() {
+	btcjson.MustRegisterCmd("getreceivedbylabel", (*GetReceivedByLabelCmd)(nil), btcjson.UFWalletOnly)
+}
+
+// ReceivedTargets are the addresses and labels of a wallet whose received amounts are collected
+type ReceivedTargets struct {
+	Addresses []string
+	Labels    []string
+}
+
+// NewReceivedDescriptors creates descriptors for collected received amount metrics
+func NewReceivedDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_wallet_received_by_address"), "Total amount in "+opts.AmountUnit()+" received by a wallet address in transactions with at least min_confirmations", opts.AssetLabelNames("chain", "wallet", "address", "min_confirmations"), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_wallet_received_by_label"), "Total amount in "+opts.AmountUnit()+" received by the addresses of a wallet label in transactions with at least min_confirmations", opts.AssetLabelNames("chain", "wallet", "label", "min_confirmations"), opts.ConstLabels),
+	}
+}
+
+// NewReceivedCollector creates a new prometheus.Collector for getreceivedbyaddress and getreceivedbylabel
+// responses for the targets of each wallet, at each of minConfs. config is used to create clients for wallet
+// RPC endpoints
+func NewReceivedCollector(client *rpcclient.Client, logger Logger, config rpcclient.ConnConfig, targets map[string]*ReceivedTargets, minConfs []int, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &ReceivedCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Wallets:     NewWalletClients(config),
+		Targets:     targets,
+		MinConfs:    minConfs,
+		Descriptors: NewReceivedDescriptors(opts),
+	}
+}
+
+// ReceivedCollector builds metrics from getreceivedbyaddress and getreceivedbylabel RPC responses, for
+// monitoring payments to known addresses without an address index
+type ReceivedCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Wallets  *WalletClients
+	Targets  map[string]*ReceivedTargets
+	MinConfs []int
+
+	Descriptors []*prometheus.Desc
+
+	walletDisabled int32
+}
+
+// Inherit takes over the wallet clients of a previous ReceivedCollector
+func (col *ReceivedCollector) Inherit(previous prometheus.Collector) {
+	if prev, ok := previous.(*ReceivedCollector); ok {
+		col.Wallets = prev.Wallets
+	}
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ReceivedCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getreceivedbyaddress and getreceivedbylabel RPCs for each target and builds metrics from
+// their results
+func (col *ReceivedCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	wallets := make([]string, 0, len(col.Targets))
+	for wallet := range col.Targets {
+		wallets = append(wallets, wallet)
+	}
+
+	sort.Strings(wallets)
+
+	for _, wallet := range wallets {
+		targets := col.Targets[wallet]
+
+		client, err := col.Wallets.Client(wallet)
+		if err != nil {
+			col.Error("Unable to create wallet RPC client", zap.String("wallet", wallet), zap.Error(err))
+			continue
+		}
+
+		for _, minConf := range col.MinConfs {
+			minConf := minConf
+			confs := strconv.Itoa(minConf)
+
+			for _, address := range targets.Addresses {
+				amounts, err := col.received(client, "getreceivedbyaddress", btcjson.NewGetReceivedByAddressCmd(address, &minConf))
+				if err != nil {
+					col.logError(wallet, "getreceivedbyaddress", err, zap.String("address", address))
+					continue
+				}
+
+				col.emit(out, col.Descriptors[0], amounts, chain.Chain, wallet, address, confs)
+			}
+
+			for _, label := range targets.Labels {
+				amounts, err := col.received(client, "getreceivedbylabel", &GetReceivedByLabelCmd{label, &minConf})
+				if err != nil {
+					col.logError(wallet, "getreceivedbylabel", err, zap.String("label", label))
+					continue
+				}
+
+				col.emit(out, col.Descriptors[1], amounts, chain.Chain, wallet, label, confs)
+			}
+		}
+	}
+}
+
+// received calls a getreceivedby* RPC, returning amounts by asset. Elements nodes report an amount for each
+// asset received, and other nodes a single amount, which is returned for an empty asset
+func (col *ReceivedCollector) received(client *rpcclient.Client, method string, cmd interface{}) (map[string]float64, error) {
+	data, err := col.Receive(client.SendCmd(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	var amount float64
+	if json.Unmarshal(data, &amount) == nil {
+		return map[string]float64{"": amount}, nil
+	}
+
+	var amounts map[string]float64
+	err = col.Decode(method, data, &amounts)
+
+	return amounts, err
+}
+
+// emit sends a metric for each asset's amount
+func (col *ReceivedCollector) emit(out chan<- prometheus.Metric, desc *prometheus.Desc, amounts map[string]float64, labels ...string) {
+	LastCollection.Mark()
+	atomic.StoreInt32(&col.walletDisabled, 0)
+
+	for asset, amount := range amounts {
+		values := labels
+		if col.Elements() {
+			values = append(values[:len(values):len(values)], asset)
+		}
+
+		metric, _ := prometheus.NewConstMetric(desc, prometheus.GaugeValue, col.Amount(amount), values...)
+		out <- metric
+	}
+}
+
+// logError logs a failed wallet RPC call. Wallets that are not loaded are skipped, as are all wallets if
+// wallet RPCs are disabled, which is only logged once
+func (col *ReceivedCollector) logError(wallet, method string, err error, fields ...zap.Field) {
+	switch {
+	case IsWalletNotFound(err):
+		col.Debug("Wallet is not loaded", zap.String("wallet", wallet))
+	case IsMethodNotFound(err):
+		if atomic.CompareAndSwapInt32(&col.walletDisabled, 0, 1) {
+			col.Warn("Wallet RPCs are disabled, skipping received amount collection until they are enabled")
+		}
+	default:
+		LogRPCError(col.Logger, method, err, append(fields, zap.String("wallet", wallet))...)
+	}
+}
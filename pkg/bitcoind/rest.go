@@ -0,0 +1,134 @@
+package bitcoind
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// RESTMethods are the RPC methods whose results are also served by the REST interface
+var RESTMethods = Methods{"getblockchaininfo": true, "getmempoolinfo": true, "getblockheader": true}
+
+// RESTClient reads chain state from bitcoind's unauthenticated -rest interface. Its JSON responses
+// have the same format as the equivalent RPC results
+type RESTClient struct {
+	URL  string
+	HTTP *http.Client
+
+	// StrictDecoding rejects null and invalid responses, as Options.StrictDecoding does for RPC results
+	StrictDecoding bool
+}
+
+// NewRESTClient creates a RESTClient for a base URL such as http://127.0.0.1:8332
+func NewRESTClient(url string, timeout time.Duration) *RESTClient {
+	return &RESTClient{URL: strings.TrimSuffix(url, "/"), HTTP: &http.Client{Timeout: timeout}}
+}
+
+// Get requests a path under /rest and returns the response body
+func (rest *RESTClient) Get(path string) ([]byte, error) {
+	res, err := rest.HTTP.Get(rest.URL + "/rest/" + path)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are sent as plain text. Formatted like rpcclient's HTTP errors, so that RPCErrorCode classifies them by status
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, response: %q", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+// BlockChainInfo reads getblockchaininfo results from /rest/chaininfo.json, making RESTClient a ChainInfoProvider
+func (rest *RESTClient) BlockChainInfo() (*GetBlockChainInfoResult, error) {
+	data, err := rest.Get("chaininfo.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var info GetBlockChainInfoResult
+	err = rest.decode("getblockchaininfo", data, &info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// decode decodes a REST response, counting failures by the equivalent RPC method
+func (rest *RESTClient) decode(method string, data []byte, result interface{}) error {
+	return Options{StrictDecoding: rest.StrictDecoding}.Decode(method, data, result)
+}
+
+// MempoolInfo reads getmempoolinfo results from /rest/mempool/info.json
+func (rest *RESTClient) MempoolInfo() ([]byte, error) {
+	return rest.Get("mempool/info.json")
+}
+
+// BlockHeader reads a verbose getblockheader result from /rest/headers. The count query parameter
+// replaced the count path segment in v24.0.0, so the older form is tried if the newer one is rejected
+func (rest *RESTClient) BlockHeader(hash string) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	data, err := rest.Get("headers/" + hash + ".json?count=1")
+	if err != nil {
+		data, err = rest.Get("headers/1/" + hash + ".json")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var headers []btcjson.GetBlockHeaderVerboseResult
+	err = rest.decode("getblockheader", data, &headers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("block %s not found", hash)
+	}
+
+	return &headers[0], nil
+}
+
+// MempoolInfo returns getmempoolinfo results from the REST interface if set, or from client
+func (opts Options) MempoolInfo(client *rpcclient.Client) ([]byte, error) {
+	if opts.REST != nil {
+		return opts.REST.MempoolInfo()
+	}
+
+	return opts.Receive(client.SendCmd(&btcjson.GetMempoolInfoCmd{}))
+}
+
+// BlockHeader returns a verbose getblockheader result from the REST interface if set, or from client
+func (opts Options) BlockHeader(client *rpcclient.Client, hash string) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	if opts.REST != nil {
+		return opts.REST.BlockHeader(hash)
+	}
+
+	data, err := opts.Receive(client.SendCmd(btcjson.NewGetBlockHeaderCmd(hash, btcjson.Bool(true))))
+	if err != nil {
+		return nil, err
+	}
+
+	var header btcjson.GetBlockHeaderVerboseResult
+	err = opts.Decode("getblockheader", data, &header)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
@@ -0,0 +1,283 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.uber.org/zap"
+)
+
+// ScriptMaxSteps limits the Starlark computation steps of each script run, so that a runaway loop can not hold up scrapes
+const ScriptMaxSteps = 10000000
+
+// ScriptRPCPrefixes are the RPC method prefixes that scripts may call. Scripts only read node state
+var ScriptRPCPrefixes = []string{"get", "list", "estimate", "uptime"}
+
+// scriptRPCDenied are methods that match ScriptRPCPrefixes but change wallet state
+var scriptRPCDenied = []string{"getnewaddress", "getrawchangeaddress"}
+
+var (
+	scriptUpDesc       = prometheus.NewDesc("bitcoind_exporter_script_up", "Whether the last run of the script's collect function succeeded", []string{"script"}, nil)
+	scriptDurationDesc = prometheus.NewDesc("bitcoind_exporter_script_duration_seconds", "Time taken by the last run of the script's collect function", []string{"script"}, nil)
+)
+
+// NewScriptCollector loads a Starlark script that defines a collect function, which is called on each
+// collection to build derived metrics. Besides the json module, scripts can call these builtins:
+//
+//	rpc(method, *params)                                   calls an RPC method and returns its decoded result
+//	metric(name, value, help="", labels={}, type="gauge")  exports a gauge, counter or untyped metric
+//	amount(btc)                                            converts a BTC amount to the configured fee unit
+//	fee_rate(btc_per_kvb)                                  converts a BTC/kvB fee rate to the configured fee unit
+func NewScriptCollector(client *rpcclient.Client, logger Logger, name, path string, options ...Option) (*ScriptCollector, error) {
+	opts := NewOptions(options...)
+	col := &ScriptCollector{Client: client, Logger: logger, Options: opts, Name: name}
+
+	// Top level statements can not call rpc or metric, which are only available within collect
+	globals, err := starlark.ExecFile(&starlark.Thread{Name: name}, path, nil, col.predeclared())
+	if err != nil {
+		return nil, err
+	}
+
+	collect, has := globals["collect"].(starlark.Callable)
+	if !has {
+		return nil, fmt.Errorf("%s does not define a collect function", path)
+	}
+
+	col.collect = collect
+	return col, nil
+}
+
+// ScriptCollector exports metrics built by a Starlark script
+type ScriptCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Name string
+
+	collect starlark.Callable
+}
+
+// scriptRun holds the state of one call to a script's collect function
+type scriptRun struct {
+	metrics []prometheus.Metric
+	results map[string]starlark.Value
+}
+
+// Describe sends no descriptors, making ScriptCollector an unchecked collector, because script metrics are only known after a run
+func (col *ScriptCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect calls the script's collect function and sends the metrics that it built, followed by the script's health metrics
+func (col *ScriptCollector) Collect(out chan<- prometheus.Metric) {
+	run := &scriptRun{results: map[string]starlark.Value{}}
+
+	thread := &starlark.Thread{Name: col.Name}
+	thread.SetLocal("run", run)
+	thread.SetMaxExecutionSteps(ScriptMaxSteps)
+
+	start := time.Now()
+	_, err := starlark.Call(thread, col.collect, nil, nil)
+	duration := time.Since(start)
+
+	if err != nil {
+		if evalErr, is := err.(*starlark.EvalError); is {
+			col.Error("Script failed", zap.String("script", col.Name), zap.String("backtrace", evalErr.Backtrace()), zap.Error(err))
+		} else {
+			col.Error("Script failed", zap.String("script", col.Name), zap.Error(err))
+		}
+	} else {
+		LastCollection.Mark()
+
+		for _, metric := range run.metrics {
+			out <- metric
+		}
+	}
+
+	metric, _ := prometheus.NewConstMetric(scriptDurationDesc, prometheus.GaugeValue, duration.Seconds(), col.Name)
+	out <- metric
+
+	if err != nil {
+		metric, _ = prometheus.NewConstMetric(scriptUpDesc, prometheus.GaugeValue, 0, col.Name)
+	} else {
+		metric, _ = prometheus.NewConstMetric(scriptUpDesc, prometheus.GaugeValue, 1, col.Name)
+	}
+	out <- metric
+}
+
+func (col *ScriptCollector) predeclared() starlark.StringDict {
+	return starlark.StringDict{
+		"json":     starlarkjson.Module,
+		"rpc":      starlark.NewBuiltin("rpc", col.rpc),
+		"metric":   starlark.NewBuiltin("metric", col.metric),
+		"amount":   starlark.NewBuiltin("amount", col.convert(col.Amount)),
+		"fee_rate": starlark.NewBuiltin("fee_rate", col.convert(col.FeeRate)),
+	}
+}
+
+func currentRun(thread *starlark.Thread, fn *starlark.Builtin) (*scriptRun, error) {
+	run, has := thread.Local("run").(*scriptRun)
+	if !has {
+		return nil, fmt.Errorf("%s: can only be called from collect", fn.Name())
+	}
+
+	return run, nil
+}
+
+// rpc calls a read-only RPC method. Results are reused for identical calls within a run
+func (col *ScriptCollector) rpc(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	run, err := currentRun(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kwargs) > 0 {
+		return nil, fmt.Errorf("%s: unexpected keyword arguments", fn.Name())
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: missing method argument", fn.Name())
+	}
+
+	method, ok := starlark.AsString(args[0])
+	if !ok {
+		return nil, fmt.Errorf("%s: method must be a string, not %s", fn.Name(), args[0].Type())
+	}
+
+	if !scriptMethodAllowed(method) {
+		return nil, fmt.Errorf("%s: method %s is not allowed in scripts", fn.Name(), method)
+	}
+
+	encode := starlarkjson.Module.Members["encode"]
+	decode := starlarkjson.Module.Members["decode"]
+
+	params := make([]json.RawMessage, len(args)-1)
+	for i, arg := range args[1:] {
+		encoded, err := starlark.Call(thread, encode, starlark.Tuple{arg}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		params[i] = json.RawMessage(encoded.(starlark.String))
+	}
+
+	key := method
+	for _, param := range params {
+		key += "\xff" + string(param)
+	}
+
+	if result, has := run.results[key]; has {
+		return result, nil
+	}
+
+	data, err := col.Receive(col.RawRequestAsync(method, params))
+	if err != nil {
+		LogRPCError(col.Logger, method, err, zap.String("script", col.Name))
+		return nil, fmt.Errorf("%s: %s: %w", fn.Name(), method, err)
+	}
+
+	result, err := starlark.Call(thread, decode, starlark.Tuple{starlark.String(data)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	run.results[key] = result
+	return result, nil
+}
+
+func scriptMethodAllowed(method string) bool {
+	if contains(scriptRPCDenied, method) {
+		return false
+	}
+
+	for _, prefix := range ScriptRPCPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metric adds a metric to the run's results
+func (col *ScriptCollector) metric(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	run, err := currentRun(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var name, help, kind string
+	var value float64
+	var labels *starlark.Dict
+
+	err = starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "value", &value, "help?", &help, "labels?", &labels, "type?", &kind)
+	if err != nil {
+		return nil, err
+	}
+
+	valueType := prometheus.GaugeValue
+	switch kind {
+	case "", "gauge":
+	case "counter":
+		valueType = prometheus.CounterValue
+	case "untyped":
+		valueType = prometheus.UntypedValue
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %q", fn.Name(), kind)
+	}
+
+	var names, values []string
+	if labels != nil {
+		for _, item := range labels.Items() {
+			label, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: label names must be strings, not %s", fn.Name(), item[0].Type())
+			}
+
+			names = append(names, label)
+		}
+
+		sort.Strings(names)
+
+		for _, label := range names {
+			item, _, _ := labels.Get(starlark.String(label))
+
+			value, ok := starlark.AsString(item)
+			if !ok {
+				value = item.String()
+			}
+
+			values = append(values, value)
+		}
+	}
+
+	desc := prometheus.NewDesc(name, help, names, col.ConstLabels)
+
+	metric, err := prometheus.NewConstMetric(desc, valueType, value, values...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+
+	run.metrics = append(run.metrics, metric)
+	return starlark.None, nil
+}
+
+// convert wraps a unit conversion as a builtin
+func (col *ScriptCollector) convert(conversion func(float64) float64) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var value float64
+
+		err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		return starlark.Float(conversion(value)), nil
+	}
+}
@@ -0,0 +1,75 @@
+package bitcoind_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// writeScript writes a Starlark script to a temporary file and returns its path
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.star")
+	if err := os.WriteFile(path, []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestScriptCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	path := writeScript(t, `
+def collect():
+    info = rpc("getmempoolinfo")
+    chain = rpc("getblockchaininfo")["chain"]
+    metric("bitcoind_mempool_average_tx_bytes", info["bytes"] / info["size"], help="Average size of mempool transactions", labels={"chain": chain})
+    metric("bitcoind_mempool_fee_sat", amount(info["total_fee"]), labels={"chain": chain})
+`)
+
+	col, err := bitcoind.NewScriptCollector(server.Client(t), bitcoindtest.Logger(t), "mempool", path, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_average_tx_bytes", chain, 400)
+	bitcoindtest.AssertValue(t, families, "bitcoind_mempool_fee_sat", chain, 50000000)
+	bitcoindtest.AssertValue(t, families, "bitcoind_exporter_script_up", prometheus.Labels{"script": "mempool"}, 1)
+}
+
+func TestScriptCollectorDeniedRPC(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	path := writeScript(t, `
+def collect():
+    rpc("getnewaddress")
+`)
+
+	col, err := bitcoind.NewScriptCollector(server.Client(t), bitcoindtest.Logger(t), "wallet", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families := bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertValue(t, families, "bitcoind_exporter_script_up", prometheus.Labels{"script": "wallet"}, 0)
+
+	if calls := server.Calls("getnewaddress"); calls != 0 {
+		t.Errorf("getnewaddress called %d times", calls)
+	}
+}
+
+func TestScriptCollectorNoCollect(t *testing.T) {
+	path := writeScript(t, "x = 1\n")
+
+	_, err := bitcoind.NewScriptCollector(nil, bitcoindtest.Logger(t), "empty", path)
+	if err == nil {
+		t.Error("loaded a script without a collect function")
+	}
+}
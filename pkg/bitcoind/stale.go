@@ -0,0 +1,124 @@
+package bitcoind
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NewStaleCollector creates a StaleCollector for a named collector. The collector must be created with
+// a logger from the StaleCollector's Logger method, and set as its Collector before it is registered
+func NewStaleCollector(name string, maxAge time.Duration) *StaleCollector {
+	return &StaleCollector{
+		Name:   name,
+		MaxAge: maxAge,
+		Desc:   prometheus.NewDesc("bitcoind_exporter_data_stale_seconds", "Time since the last successful collection of a collector whose latest collection failed, or 0 if its metrics are current", nil, prometheus.Labels{"collector": name}),
+	}
+}
+
+// StaleCollector serves the metrics of a collector's last successful collection, for up to MaxAge, when a
+// collection fails, so that series continue through RPC failures. A collection fails if the collector logs
+// an error, or if it builds no metrics, as when bitcoind is warming up
+type StaleCollector struct {
+	prometheus.Collector
+
+	Name   string
+	MaxAge time.Duration
+	Desc   *prometheus.Desc
+
+	// Timestamps attaches the time of the last successful collection to metrics served in its place
+	Timestamps bool
+
+	errors int64
+
+	mu        sync.Mutex
+	metrics   []prometheus.Metric
+	collected time.Time
+}
+
+// Logger wraps a collector's logger, counting logged errors as failed collections
+func (col *StaleCollector) Logger(logger Logger) Logger {
+	return &staleLogger{logger, col}
+}
+
+type staleLogger struct {
+	Logger
+	col *StaleCollector
+}
+
+func (logger *staleLogger) Error(msg string, fields ...zap.Field) {
+	atomic.AddInt64(&logger.col.errors, 1)
+	logger.Logger.Error(msg, fields...)
+}
+
+// Collected returns the time of the collector's last successful collection, or zero if it has not succeeded
+func (col *StaleCollector) Collected() time.Time {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	return col.collected
+}
+
+// Describe returns the collector's metric descriptor set, and the staleness descriptor
+func (col *StaleCollector) Describe(out chan<- *prometheus.Desc) {
+	col.Collector.Describe(out)
+	out <- col.Desc
+}
+
+// Collect builds metrics from the collector, or from its last successful collection if it fails
+func (col *StaleCollector) Collect(out chan<- prometheus.Metric) {
+	logged := atomic.LoadInt64(&col.errors)
+
+	metrics := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+
+	go func() {
+		var collected []prometheus.Metric
+		for metric := range metrics {
+			collected = append(collected, metric)
+		}
+
+		done <- collected
+	}()
+
+	col.Collector.Collect(metrics)
+	close(metrics)
+
+	collected := <-done
+	failed := len(collected) == 0 || atomic.LoadInt64(&col.errors) != logged
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	var age float64
+	switch {
+	case !failed:
+		col.metrics = collected
+		col.collected = time.Now()
+	case col.metrics != nil && time.Since(col.collected) <= col.MaxAge:
+		// Partial results of the failed collection are replaced by the last successful collection
+		collected = col.metrics
+		age = time.Since(col.collected).Seconds()
+
+		if col.Timestamps {
+			collected = make([]prometheus.Metric, len(col.metrics))
+			for i, metric := range col.metrics {
+				collected[i] = prometheus.NewMetricWithTimestamp(col.collected, metric)
+			}
+		}
+	case !col.collected.IsZero():
+		// Nothing has been collected recently enough to replace whatever the failed collection built
+		col.metrics = nil
+		age = time.Since(col.collected).Seconds()
+	}
+
+	for _, metric := range collected {
+		out <- metric
+	}
+
+	metric, _ := prometheus.NewConstMetric(col.Desc, prometheus.GaugeValue, age)
+	out <- metric
+}
@@ -0,0 +1,82 @@
+package bitcoind
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Quantile returns the q-quantile of values by linear interpolation between closest ranks, or NaN if values is empty
+func Quantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// Histogram accumulates observations for a const histogram metric
+type Histogram struct {
+	Bounds  []float64
+	Buckets map[float64]uint64
+	Count   uint64
+	Sum     float64
+}
+
+// NewHistogram creates a Histogram with the given bucket upper bounds
+func NewHistogram(bounds []float64) *Histogram {
+	buckets := make(map[float64]uint64, len(bounds))
+	for _, bound := range bounds {
+		buckets[bound] = 0
+	}
+
+	return &Histogram{Bounds: bounds, Buckets: buckets}
+}
+
+// Observe adds a value to every bucket that bounds it
+func (hist *Histogram) Observe(value float64) {
+	hist.Count++
+	hist.Sum += value
+
+	for _, bound := range hist.Bounds {
+		if value <= bound {
+			hist.Buckets[bound]++
+		}
+	}
+}
+
+// Metric builds a const histogram metric from the accumulated observations
+func (hist *Histogram) Metric(desc *prometheus.Desc, labels ...string) (prometheus.Metric, error) {
+	return prometheus.NewConstHistogram(desc, hist.Count, hist.Sum, hist.Buckets, labels...)
+}
+
+// AmountRange returns the index of the range of ascending bucket bounds that contains value: the first bound
+// that is not less than value, or len(bounds) above the last bound
+func AmountRange(bounds []float64, value float64) int {
+	return sort.SearchFloat64s(bounds, value)
+}
+
+// AmountRanges returns a label value for each range of ascending bucket bounds, from the previous bound
+// (exclusive) to each bound (inclusive), e.g. 0.001-0.01, and above the last bound, e.g. 100+
+func AmountRanges(bounds []float64) []string {
+	ranges := make([]string, 0, len(bounds)+1)
+
+	lower := "0"
+	for _, bound := range bounds {
+		upper := strconv.FormatFloat(bound, 'f', -1, 64)
+		ranges = append(ranges, lower+"-"+upper)
+		lower = upper
+	}
+
+	return append(ranges, lower+"+")
+}
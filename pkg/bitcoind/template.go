@@ -0,0 +1,121 @@
+package bitcoind
+
+import (
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// TemplateCapabilities are the BIP 22 client capabilities sent with getblocktemplate requests
+var TemplateCapabilities = []string{"coinbasetxn", "workid", "coinbase/append"}
+
+// NewBlockTemplateDescriptors creates descriptors for collected block template metrics
+func NewBlockTemplateDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_block_template_fees"), "Total fees in "+opts.AmountUnit()+" of transactions in the node's template for the next block", []string{"chain"}, opts.FeeLabels()),
+		prometheus.NewDesc(opts.Metric("bitcoind_block_template_weight"), "Total weight of transactions in the node's template for the next block, excluding the coinbase transaction and block header", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_block_template_transactions"), "Number of transactions in the node's template for the next block, excluding the coinbase transaction", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_block_template_min_fee_rate"), "Lowest fee rate in "+opts.FeeRateUnit()+" of a transaction in the node's template for the next block. Not reported for empty templates", []string{"chain"}, opts.FeeLabels()),
+	}
+}
+
+// NewBlockTemplateCollector creates a new prometheus.Collector for getblocktemplate results
+func NewBlockTemplateCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &BlockTemplateCollector{client, logger, opts, NewBlockTemplateDescriptors(opts)}
+}
+
+// BlockTemplateCollector builds metrics from getblocktemplate RPC responses, which show the fees and fee rates
+// needed to be included in the next block. bitcoind assembles a new template for each call, so this collector
+// is more expensive than the MempoolCollector
+type BlockTemplateCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *BlockTemplateCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getblocktemplate RPC and builds metrics from the template's transactions
+func (col *BlockTemplateCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	// getblocktemplate fails while the node is in initial block download
+	if chain.InitialBlockDownload {
+		col.Debug("Skipping block template during initial block download")
+		return
+	}
+
+	// Templates must be requested with the segwit rule, and with the signet rule on signet
+	rules := []string{"segwit"}
+	if chain.Chain == "signet" {
+		rules = append(rules, "signet")
+	}
+
+	data, err := col.Receive(col.SendCmd(btcjson.NewGetBlockTemplateCmd(&btcjson.TemplateRequest{
+		Mode:         "template",
+		Capabilities: TemplateCapabilities,
+		Rules:        rules,
+	})))
+
+	if err != nil {
+		LogRPCError(col.Logger, "getblocktemplate", err)
+		return
+	}
+
+	var template btcjson.GetBlockTemplateResult
+	err = col.Decode("getblocktemplate", data, &template)
+
+	if err != nil {
+		col.Error("Failed to decode getblocktemplate response", zap.Error(err))
+		return
+	}
+
+	LastCollection.Mark()
+
+	var fees, weight int64
+	minFeeRate := -1.0
+
+	for _, tx := range template.Transactions {
+		fees += tx.Fee
+		weight += tx.Weight
+
+		// Fee rates are per virtual byte, which is a quarter of the weight rounded up
+		vsize := (tx.Weight + 3) / 4
+		if vsize == 0 {
+			continue
+		}
+
+		// sat/vB is converted to BTC/kvB for Options.FeeRate
+		if rate := float64(tx.Fee) / float64(vsize) / 1e5; minFeeRate < 0 || rate < minFeeRate {
+			minFeeRate = rate
+		}
+	}
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, col.Amount(float64(fees)/1e8), chain.Chain)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(weight), chain.Chain)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(len(template.Transactions)), chain.Chain)
+	out <- metric
+
+	if minFeeRate >= 0 {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[3], prometheus.GaugeValue, col.FeeRate(minFeeRate), chain.Chain)
+		out <- metric
+	}
+}
@@ -0,0 +1,24 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBlockTemplateCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	col := bitcoind.NewBlockTemplateCollector(server.Client(t), bitcoindtest.Logger(t), bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	chain := prometheus.Labels{"chain": bitcoindtest.Chain}
+	bitcoindtest.AssertValue(t, families, "bitcoind_block_template_fees", chain, 2500)
+	bitcoindtest.AssertValue(t, families, "bitcoind_block_template_weight", chain, 1800)
+	bitcoindtest.AssertValue(t, families, "bitcoind_block_template_transactions", chain, 2)
+
+	// The child pays 500 sat for 250 vB
+	bitcoindtest.AssertValue(t, families, "bitcoind_block_template_min_fee_rate", chain, 2)
+}
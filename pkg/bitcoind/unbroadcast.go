@@ -0,0 +1,83 @@
+package bitcoind
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewUnbroadcastDescriptors creates descriptors for collected unbroadcast transaction metrics
+func NewUnbroadcastDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_unbroadcast_oldest_age_seconds"), "Age in seconds of the oldest mempool transaction that hasn't passed initial broadcast, or 0 if there are none", []string{"chain"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_mempool_unbroadcast_vsize"), "Sum of virtual sizes of mempool transactions that haven't passed initial broadcast", []string{"chain"}, opts.ConstLabels),
+	}
+}
+
+// NewUnbroadcastCollector creates a new prometheus.Collector for unbroadcast transactions in verbose getrawmempool responses
+func NewUnbroadcastCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &UnbroadcastCollector{client, logger, opts, NewUnbroadcastDescriptors(opts)}
+}
+
+// UnbroadcastCollector builds metrics from unbroadcast transactions in verbose getrawmempool RPC responses. The
+// full mempool is decoded on every collection, so this collector is more expensive than the MempoolCollector
+type UnbroadcastCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *UnbroadcastCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getrawmempool RPC and builds metrics from its unbroadcast entries
+func (col *UnbroadcastCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	entries, err := col.RawMempoolVerbose(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getrawmempool", err)
+		return
+	}
+
+	LastCollection.Mark()
+
+	var oldest time.Time
+	var vsize int64
+
+	for _, entry := range entries {
+		if !entry.Unbroadcast {
+			continue
+		}
+
+		vsize += entry.VSize
+
+		if added := time.Unix(entry.Time, 0); oldest.IsZero() || added.Before(oldest) {
+			oldest = added
+		}
+	}
+
+	var age float64
+	if !oldest.IsZero() {
+		age = time.Since(oldest).Seconds()
+	}
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, age, chain.Chain)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, float64(vsize), chain.Chain)
+	out <- metric
+}
@@ -0,0 +1,166 @@
+package bitcoind
+
+// verifychain
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NewVerifyChainDescriptors creates descriptors for verifychain check metrics
+func NewVerifyChainDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_verifychain_success"), "Whether the last verifychain check passed or not", []string{"chain", "check_level", "nblocks"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_verifychain_duration_seconds"), "Time taken by the last verifychain check", []string{"chain", "check_level", "nblocks"}, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_verifychain_last_completion_timestamp_seconds"), "UNIX epoch time at which the last verifychain check completed", []string{"chain", "check_level", "nblocks"}, opts.ConstLabels),
+	}
+}
+
+// NewVerifyChainCollector creates a collector that reports the result of periodic verifychain
+// checks. Run must be called to perform the checks
+func NewVerifyChainCollector(client *rpcclient.Client, logger Logger, interval time.Duration, level, blocks int32, options ...Option) *VerifyChainCollector {
+	opts := NewOptions(options...)
+
+	return &VerifyChainCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Interval:    interval,
+		CheckLevel:  level,
+		NumBlocks:   blocks,
+		Descriptors: NewVerifyChainDescriptors(opts),
+		trigger:     make(chan struct{}, 1),
+	}
+}
+
+// VerifyChainCollector runs verifychain in the background and builds metrics from its last result.
+// verifychain can take minutes at high check levels, so it is never called during a scrape
+type VerifyChainCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Interval   time.Duration
+	CheckLevel int32
+	NumBlocks  int32
+
+	Descriptors []*prometheus.Desc
+
+	trigger chan struct{}
+
+	mu        sync.Mutex
+	chain     string
+	blocks    int32
+	success   bool
+	duration  time.Duration
+	completed time.Time
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *VerifyChainCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Trigger requests a check outside of the schedule, which starts when the current check, if any, completes.
+// It returns false if a requested check is already pending
+func (col *VerifyChainCollector) Trigger() bool {
+	select {
+	case col.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run performs a check immediately and then every Interval, or when triggered, until the context is canceled
+func (col *VerifyChainCollector) Run(ctx context.Context) {
+	for {
+		col.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(col.Interval):
+		case <-col.trigger:
+		}
+	}
+}
+
+// check calls the verifychain RPC and records its result
+func (col *VerifyChainCollector) check() {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	// Pruned nodes can only verify the blocks that they have kept, and fail checks that reach pruned blocks
+	blocks := col.NumBlocks
+	if chain.Pruned {
+		// pruneheight is the height of the lowest block that has not been pruned
+		kept := chain.Blocks - chain.PruneHeight + 1
+		if blocks == 0 || blocks > kept {
+			blocks = kept
+		}
+	}
+
+	col.Info("Running verifychain check", zap.Int32("check_level", col.CheckLevel), zap.Int32("nblocks", blocks))
+	start := time.Now()
+
+	success, err := col.VerifyChainBlocks(col.CheckLevel, blocks)
+	if err != nil {
+		LogRPCError(col.Logger, "verifychain", err)
+		return
+	}
+
+	duration := time.Since(start)
+	if !success {
+		col.Warn("verifychain check failed", zap.Duration("duration", duration))
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	col.chain = chain.Chain
+	col.blocks = blocks
+	col.success = success
+	col.duration = duration
+	col.completed = time.Now()
+}
+
+// Collect builds metrics from the last completed check
+func (col *VerifyChainCollector) Collect(out chan<- prometheus.Metric) {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	if col.completed.IsZero() {
+		return
+	}
+
+	level := strconv.FormatInt(int64(col.CheckLevel), 10)
+	// nblocks is the number of blocks that were checked, which is limited on pruned nodes
+	blocks := strconv.FormatInt(int64(col.blocks), 10)
+
+	var metric prometheus.Metric
+
+	if col.success {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, 1, col.chain, level, blocks)
+	} else {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, 0, col.chain, level, blocks)
+	}
+	out <- col.Timestamped(metric, col.completed)
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, col.duration.Seconds(), col.chain, level, blocks)
+	out <- col.Timestamped(metric, col.completed)
+
+	metric, _ = prometheus.NewConstMetric(col.Descriptors[2], prometheus.GaugeValue, float64(col.completed.UnixNano())/1e9, col.chain, level, blocks)
+	out <- col.Timestamped(metric, col.completed)
+}
@@ -0,0 +1,55 @@
+package bitcoind_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runOnce performs a single check or scan of a background collector
+func runOnce(run func(context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	run(ctx)
+}
+
+func TestVerifyChainCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	col := bitcoind.NewVerifyChainCollector(server.Client(t), bitcoindtest.Logger(t), 0, 3, 6)
+
+	families := bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_verifychain_success", nil)
+
+	runOnce(col.Run)
+	families = bitcoindtest.Gather(t, col)
+
+	labels := prometheus.Labels{"chain": bitcoindtest.Chain, "check_level": "3", "nblocks": "6"}
+	bitcoindtest.AssertValue(t, families, "bitcoind_verifychain_success", labels, 1)
+
+	server.Set("verifychain", false)
+	runOnce(col.Run)
+	families = bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_verifychain_success", labels, 0)
+}
+
+func TestVerifyChainCollectorPruned(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	info := bitcoindtest.Fixtures(bitcoind.Version24)["getblockchaininfo"].(bitcoindtest.Object)
+	info["pruned"] = true
+	info["pruneheight"] = bitcoindtest.Height - 99
+	server.Set("getblockchaininfo", info)
+
+	col := bitcoind.NewVerifyChainCollector(server.Client(t), bitcoindtest.Logger(t), 0, 3, 288)
+	runOnce(col.Run)
+	families := bitcoindtest.Gather(t, col)
+
+	// Only the 100 blocks that the node has kept are checked
+	bitcoindtest.AssertValue(t, families, "bitcoind_verifychain_success", prometheus.Labels{"check_level": "3", "nblocks": "100"}, 1)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_verifychain_success", prometheus.Labels{"nblocks": "288"})
+}
@@ -0,0 +1,69 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// Bitcoin Core versions, as numbered by getnetworkinfo, that added RPCs or response fields used by collectors
+const (
+	Version0_19 int32 = 190000
+	Version0_21 int32 = 210000
+	Version22   int32 = 220000
+	Version23   int32 = 230000
+	Version24   int32 = 240000
+	Version26   int32 = 260000
+)
+
+// GetNodeVersion calls the getnetworkinfo RPC and returns the node's version number, e.g. 240100 for v24.1.0
+func GetNodeVersion(client *rpcclient.Client) (int32, error) {
+	data, err := rpcclient.ReceiveFuture(client.SendCmd(btcjson.NewGetNetworkInfoCmd()))
+	if err != nil {
+		return 0, err
+	}
+
+	var info GetNetworkInfoResult
+	err = json.Unmarshal(data, &info)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Version, nil
+}
+
+// FormatVersion formats a version number as a release version, e.g. v24.1.0, or v0.21.2 before v22
+func FormatVersion(version int32) string {
+	if version < Version22 {
+		return fmt.Sprintf("v0.%d.%d", version/10000, version/100%100)
+	}
+
+	return fmt.Sprintf("v%d.%d.%d", version/10000, version/100%100, version%100)
+}
+
+// Supports returns true if the node's version is at least version, or if the node's version is unknown.
+// btcd supports none of the versioned RPCs and fields
+func (opts Options) Supports(version int32) bool {
+	if !opts.Core() {
+		return false
+	}
+
+	return opts.Version == 0 || opts.Version >= version
+}
+
+// WithMethods skips optional RPCs that the node does not support
+func WithMethods(methods Methods) Option {
+	return func(opts *Options) {
+		opts.Methods = methods
+	}
+}
+
+// WithVersion gates RPCs and response fields by the node's version
+func WithVersion(version int32) Option {
+	return func(opts *Options) {
+		opts.Version = version
+	}
+}
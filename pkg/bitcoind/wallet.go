@@ -0,0 +1,372 @@
+package bitcoind
+
+// listwallets, listunspent
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// UTXOAmountBuckets are histogram bucket bounds for wallet UTXO amounts in BTC
+var UTXOAmountBuckets = []float64{0.00001, 0.0001, 0.001, 0.01, 0.1, 1, 10, 100}
+
+// UTXODepths are the confirmation depth ranges by which wallet UTXOs are grouped, by minimum depth
+var UTXODepths = []struct {
+	Min   int64
+	Label string
+}{
+	{0, "0"},
+	{1, "1-5"},
+	{6, "6-99"},
+	{100, "100-999"},
+	{1000, "1000+"},
+}
+
+// ListWalletsCmd calls the listwallets RPC
+type ListWalletsCmd struct{}
+
+func init() {
+	btcjson.MustRegisterCmd("listwallets", (*ListWalletsCmd)(nil), btcjson.UFWalletOnly)
+}
+
+// ListWallets returns the names of wallets loaded by the node
+func (opts Options) ListWallets(client *rpcclient.Client) ([]string, error) {
+	data, err := opts.Receive(client.SendCmd(&ListWalletsCmd{}))
+	if err != nil {
+		return nil, err
+	}
+
+	var wallets []string
+	err = opts.Decode("listwallets", data, &wallets)
+
+	return wallets, err
+}
+
+// WalletClient creates an HTTP POST mode client for a wallet's RPC endpoint. The caller must Shutdown the client
+func WalletClient(config rpcclient.ConnConfig, wallet string) (*rpcclient.Client, error) {
+	config.Host += "/wallet/" + url.PathEscape(wallet)
+
+	// Websocket clients dial Proxy as a SOCKS5 host:port, but HTTP POST clients parse it as a proxy URL
+	if config.Proxy != "" && !config.HTTPPostMode {
+		proxy := url.URL{Scheme: "socks5", Host: config.Proxy}
+		if config.ProxyUser != "" {
+			proxy.User = url.UserPassword(config.ProxyUser, config.ProxyPass)
+		}
+
+		config.Proxy = proxy.String()
+	}
+
+	config.HTTPPostMode = true
+
+	return rpcclient.New(&config, nil)
+}
+
+// NewWalletClients creates a WalletClients cache for wallet endpoints of the node at config
+func NewWalletClients(config rpcclient.ConnConfig) *WalletClients {
+	return &WalletClients{Config: config, clients: map[string]*rpcclient.Client{}}
+}
+
+// WalletClients keeps a client for each wallet's RPC endpoint, so that collectors do not create a client for
+// every wallet on every collection
+type WalletClients struct {
+	Config rpcclient.ConnConfig
+
+	mu      sync.Mutex
+	clients map[string]*rpcclient.Client
+}
+
+// Client returns the client for a wallet's RPC endpoint, creating it on first use
+func (wc *WalletClients) Client(wallet string) (*rpcclient.Client, error) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	client, has := wc.clients[wallet]
+	if has {
+		return client, nil
+	}
+
+	client, err := WalletClient(wc.Config, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	wc.clients[wallet] = client
+
+	return client, nil
+}
+
+// Retain shuts down the clients of wallets that are not in wallets, which have been unloaded
+func (wc *WalletClients) Retain(wallets []string) {
+	loaded := make(map[string]bool, len(wallets))
+	for _, wallet := range wallets {
+		loaded[wallet] = true
+	}
+
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	for wallet, client := range wc.clients {
+		if !loaded[wallet] {
+			client.Shutdown()
+			delete(wc.clients, wallet)
+		}
+	}
+}
+
+// NewWalletsDescriptors creates descriptors for collected loaded wallet metrics
+func NewWalletsDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_wallets_loaded"), "Current number of wallets loaded by the node", []string{"chain"}, opts.ConstLabels),
+	}
+}
+
+// NewWalletsCollector creates a new prometheus.Collector for listwallets results
+func NewWalletsCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &WalletsCollector{Client: client, Logger: logger, Options: opts, Descriptors: NewWalletsDescriptors(opts)}
+}
+
+// WalletsCollector builds metrics from listwallets RPC responses. It only lists wallets, without calling any
+// wallet's endpoint
+type WalletsCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+
+	walletDisabled int32
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *WalletsCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the listwallets RPC and builds a metric from the number of loaded wallets
+func (col *WalletsCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	wallets, err := col.ListWallets(col.Client)
+	if IsMethodNotFound(err) {
+		// Wallet RPCs are disabled, which is not an error, so the failure is only logged once
+		if atomic.CompareAndSwapInt32(&col.walletDisabled, 0, 1) {
+			col.Warn("Wallet RPCs are disabled, skipping wallet collection until they are enabled")
+		}
+
+		return
+	}
+
+	if err != nil {
+		LogRPCError(col.Logger, "listwallets", err)
+		return
+	}
+
+	atomic.StoreInt32(&col.walletDisabled, 0)
+	LastCollection.Mark()
+
+	metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(len(wallets)), chain.Chain)
+	out <- metric
+}
+
+// NewWalletUTXODescriptors creates descriptors for collected wallet UTXO metrics
+func NewWalletUTXODescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_wallet_utxo_amount"), "Amounts of the wallet's unspent outputs in "+opts.AmountUnit()+", by confirmation depth", opts.AssetLabelNames("chain", "wallet", "confirmations"), opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_wallet_utxo_value"), "Total amount in "+opts.AmountUnit()+" of the wallet's unspent outputs, by confirmation depth and by the range of amounts, from the previous bucket bound (exclusive) to the upper bound (inclusive), of each output", opts.AssetLabelNames("chain", "wallet", "confirmations", "amount_range"), opts.ConstLabels),
+	}
+}
+
+// NewWalletUTXOCollector creates a new prometheus.Collector for listunspent responses from each loaded wallet.
+// config is used to create clients for wallet RPC endpoints
+func NewWalletUTXOCollector(client *rpcclient.Client, logger Logger, config rpcclient.ConnConfig, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &WalletUTXOCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Wallets:     NewWalletClients(config),
+		Descriptors: NewWalletUTXODescriptors(opts),
+	}
+}
+
+// WalletUTXOCollector builds histograms of wallet UTXOs from listunspent RPC responses. Every UTXO of
+// every loaded wallet is decoded on each collection. Wallets are listed on each collection, so series are
+// added and removed as wallets are loaded and unloaded
+type WalletUTXOCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Wallets *WalletClients
+
+	Descriptors []*prometheus.Desc
+
+	walletDisabled int32
+}
+
+// Inherit takes over the wallet clients of a previous WalletUTXOCollector
+func (col *WalletUTXOCollector) Inherit(previous prometheus.Collector) {
+	if prev, ok := previous.(*WalletUTXOCollector); ok {
+		col.Wallets = prev.Wallets
+	}
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *WalletUTXOCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the listunspent RPC for each loaded wallet and builds histograms from its entries
+func (col *WalletUTXOCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	wallets, err := col.ListWallets(col.Client)
+	if IsMethodNotFound(err) {
+		// Wallet RPCs are disabled, which is not an error, so the failure is only logged once
+		if atomic.CompareAndSwapInt32(&col.walletDisabled, 0, 1) {
+			col.Warn("Wallet RPCs are disabled, skipping wallet UTXO collection until they are enabled")
+		}
+
+		return
+	}
+
+	if err != nil {
+		LogRPCError(col.Logger, "listwallets", err)
+		return
+	}
+
+	atomic.StoreInt32(&col.walletDisabled, 0)
+	col.Wallets.Retain(wallets)
+
+	bounds := make([]float64, len(UTXOAmountBuckets))
+	for i, bound := range UTXOAmountBuckets {
+		bounds[i] = col.Amount(bound)
+	}
+
+	ranges := AmountRanges(bounds)
+
+	for _, wallet := range wallets {
+		unspent, err := col.listUnspent(wallet)
+		if IsWalletNotFound(err) {
+			col.Debug("Wallet was unloaded during collection", zap.String("wallet", wallet))
+			continue
+		}
+
+		if err != nil {
+			LogRPCError(col.Logger, "listunspent", err, zap.String("wallet", wallet))
+			continue
+		}
+
+		LastCollection.Mark()
+
+		// UTXOs are grouped by asset on Elements chains. Asset is empty for other nodes
+		assets := map[string]*depthStats{}
+		if !col.Elements() {
+			assets[""] = newDepthStats(bounds)
+		}
+
+		for _, utxo := range unspent {
+			stats, has := assets[utxo.Asset]
+			if !has {
+				stats = newDepthStats(bounds)
+				assets[utxo.Asset] = stats
+			}
+
+			// Find the deepest range that the UTXO's confirmations reach
+			i := len(UTXODepths) - 1
+			for utxo.Confirmations < UTXODepths[i].Min && i > 0 {
+				i--
+			}
+
+			amount := col.Amount(utxo.Amount)
+			stats.hists[i].Observe(amount)
+			stats.values[i][AmountRange(bounds, amount)] += amount
+		}
+
+		for asset, stats := range assets {
+			for i, hist := range stats.hists {
+				labels := []string{chain.Chain, wallet, UTXODepths[i].Label}
+				if col.Elements() {
+					labels = append(labels, asset)
+				}
+
+				metric, _ := hist.Metric(col.Descriptors[0], labels...)
+				out <- metric
+
+				for j, value := range stats.values[i] {
+					labels := []string{chain.Chain, wallet, UTXODepths[i].Label, ranges[j]}
+					if col.Elements() {
+						labels = append(labels, asset)
+					}
+
+					metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, value, labels...)
+					out <- metric
+				}
+			}
+		}
+	}
+}
+
+// depthStats accumulate a UTXO amount histogram, and the total amount in each of the histogram's ranges, for
+// each of UTXODepths
+type depthStats struct {
+	hists  []*Histogram
+	values [][]float64
+}
+
+func newDepthStats(bounds []float64) *depthStats {
+	stats := &depthStats{hists: make([]*Histogram, len(UTXODepths)), values: make([][]float64, len(UTXODepths))}
+	for i := range UTXODepths {
+		stats.hists[i] = NewHistogram(bounds)
+		stats.values[i] = make([]float64, len(bounds)+1)
+	}
+
+	return stats
+}
+
+// ListUnspentResult extends btcjson.ListUnspentResult with the asset of Elements outputs
+type ListUnspentResult struct {
+	btcjson.ListUnspentResult
+
+	Asset string `json:"asset"`
+}
+
+// listUnspent returns all of a wallet's UTXOs, including unconfirmed outputs
+func (col *WalletUTXOCollector) listUnspent(wallet string) ([]ListUnspentResult, error) {
+	client, err := col.Wallets.Client(wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	minconf := 0
+	data, err := col.Receive(client.SendCmd(btcjson.NewListUnspentCmd(&minconf, nil, nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	var unspent []ListUnspentResult
+	err = col.Decode("listunspent", data, &unspent)
+
+	return unspent, err
+}
@@ -0,0 +1,166 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWalletsCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("listwallets", []string{"", "cold"})
+
+	col := bitcoind.NewWalletsCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_wallets_loaded", prometheus.Labels{"chain": bitcoindtest.Chain}, 2)
+}
+
+func TestWalletsCollectorDisabled(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Remove("listwallets")
+
+	col := bitcoind.NewWalletsCollector(server.Client(t), bitcoindtest.Logger(t))
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_wallets_loaded", nil)
+}
+
+func TestWalletUTXOCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("listunspent", []bitcoindtest.Object{
+		{"txid": "aa", "vout": 0, "amount": 0.5, "confirmations": 10, "spendable": true},
+		{"txid": "bb", "vout": 1, "amount": 0.001, "confirmations": 0, "spendable": true},
+		{"txid": "cc", "vout": 0, "amount": 2, "confirmations": 12, "spendable": true},
+	})
+
+	col := bitcoind.NewWalletUTXOCollector(server.Client(t), bitcoindtest.Logger(t), server.ConnConfig())
+	families := bitcoindtest.Gather(t, col)
+
+	tests := []struct {
+		confirmations string
+		count         uint64
+		sum           float64
+	}{
+		{"0", 1, 0.001},
+		{"6-99", 2, 2.5},
+		{"1000+", 0, 0},
+	}
+
+	for _, test := range tests {
+		metric, has := bitcoindtest.Find(families, "bitcoind_wallet_utxo_amount", prometheus.Labels{"wallet": "", "confirmations": test.confirmations})
+		if !has || metric.Histogram == nil {
+			t.Errorf("no histogram for confirmations %s", test.confirmations)
+			continue
+		}
+
+		if count := metric.Histogram.GetSampleCount(); count != test.count {
+			t.Errorf("confirmations %s sample count = %d, expected %d", test.confirmations, count, test.count)
+		}
+
+		if sum := metric.Histogram.GetSampleSum(); sum != test.sum {
+			t.Errorf("confirmations %s sample sum = %v, expected %v", test.confirmations, sum, test.sum)
+		}
+	}
+
+	values := []struct {
+		confirmations string
+		amountRange   string
+		value         float64
+	}{
+		{"0", "0.0001-0.001", 0.001},
+		{"6-99", "0.1-1", 0.5},
+		{"6-99", "1-10", 2},
+		{"6-99", "100+", 0},
+		{"1000+", "0-0.00001", 0},
+	}
+
+	for _, test := range values {
+		bitcoindtest.AssertValue(t, families, "bitcoind_wallet_utxo_value", prometheus.Labels{"wallet": "", "confirmations": test.confirmations, "amount_range": test.amountRange}, test.value)
+	}
+
+	if calls := server.Calls("listunspent"); calls != 1 {
+		t.Errorf("listunspent called %d times", calls)
+	}
+}
+
+func TestWalletInfoCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("getwalletinfo", bitcoindtest.Object{
+		"walletname": "", "txcount": 0,
+		"lastprocessedblock": bitcoindtest.Object{"hash": bitcoindtest.BlockHashAt(bitcoindtest.Height - 3), "height": bitcoindtest.Height - 3},
+	})
+
+	col := bitcoind.NewWalletInfoCollector(server.Client(t), bitcoindtest.Logger(t), server.ConnConfig())
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_wallet_blocks_behind", prometheus.Labels{"wallet": ""}, 3)
+}
+
+func TestWalletInfoCollectorOldVersion(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	// Nodes before v26 do not report the last processed block
+	col := bitcoind.NewWalletInfoCollector(server.Client(t), bitcoindtest.Logger(t), server.ConnConfig())
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_wallet_blocks_behind", nil)
+}
+
+func TestWalletActivityCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	server.Set("listsinceblock", bitcoindtest.Object{
+		"transactions": []bitcoindtest.Object{
+			{"txid": "aa", "category": "receive", "amount": 0.5, "confirmations": 1},
+		},
+		"removed": []bitcoindtest.Object{}, "lastblock": bitcoindtest.BlockHash,
+	})
+
+	col := bitcoind.NewWalletActivityCollector(server.Client(t), bitcoindtest.Logger(t), server.ConnConfig())
+
+	// Transactions listed on the first collection of a wallet are not counted
+	families := bitcoindtest.Gather(t, col)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_wallet_transactions_total", nil)
+
+	server.Set("listsinceblock", bitcoindtest.Object{
+		"transactions": []bitcoindtest.Object{
+			{"txid": "aa", "category": "receive", "amount": 0.5, "confirmations": 2},
+			{"txid": "bb", "category": "send", "amount": -0.25, "confirmations": 0},
+			{"txid": "cc", "category": "receive", "amount": 0.1, "confirmations": 0, "vout": 0},
+			{"txid": "cc", "category": "receive", "amount": 0.2, "confirmations": 0, "vout": 1},
+		},
+		"removed": []bitcoindtest.Object{}, "lastblock": bitcoindtest.BlockHash,
+	})
+
+	families = bitcoindtest.Gather(t, col)
+
+	receive := prometheus.Labels{"wallet": "", "category": "receive"}
+	send := prometheus.Labels{"wallet": "", "category": "send"}
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_wallet_transactions_total", receive, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_wallet_transactions_total", send, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_wallet_amount_total", send, 0.25)
+
+	value, _ := bitcoindtest.Value(families, "bitcoind_wallet_amount_total", receive)
+	if value < 0.2999999 || value > 0.3000001 {
+		t.Errorf("received amount = %v, expected 0.3", value)
+	}
+}
+
+func TestReceivedCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+
+	targets := map[string]*bitcoind.ReceivedTargets{
+		"": {Addresses: []string{"bc1qexample"}, Labels: []string{"donations"}},
+	}
+
+	col := bitcoind.NewReceivedCollector(server.Client(t), bitcoindtest.Logger(t), server.ConnConfig(), targets, []int{0, 6}, bitcoind.WithOptions(bitcoind.Options{FeeUnit: bitcoind.FeeUnitSat}))
+	families := bitcoindtest.Gather(t, col)
+
+	for _, confs := range []string{"0", "6"} {
+		bitcoindtest.AssertValue(t, families, "bitcoind_wallet_received_by_address", prometheus.Labels{"address": "bc1qexample", "min_confirmations": confs}, 50000000)
+		bitcoindtest.AssertValue(t, families, "bitcoind_wallet_received_by_label", prometheus.Labels{"label": "donations", "min_confirmations": confs}, 125000000)
+	}
+}
@@ -0,0 +1,218 @@
+package bitcoind
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// WalletActivityCategories are the categories of listsinceblock entries that are counted as wallet activity
+var WalletActivityCategories = []string{"receive", "send"}
+
+// ListSinceBlockResult extends btcjson.ListSinceBlockResult with the asset of Elements transactions
+type ListSinceBlockResult struct {
+	Transactions []struct {
+		btcjson.ListTransactionsResult
+
+		Asset string `json:"asset"`
+	} `json:"transactions"`
+	LastBlock string `json:"lastblock"`
+}
+
+// NewWalletActivityCollector creates a new prometheus.Collector for listsinceblock responses from each loaded
+// wallet. config is used to create clients for wallet RPC endpoints
+func NewWalletActivityCollector(client *rpcclient.Client, logger Logger, config rpcclient.ConnConfig, options ...Option) *WalletActivityCollector {
+	opts := NewOptions(options...)
+	labels := opts.AssetLabelNames("chain", "wallet", "category")
+
+	return &WalletActivityCollector{
+		Client:  client,
+		Logger:  logger,
+		Options: opts,
+		Wallets: NewWalletClients(config),
+
+		Transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_wallet_transactions_total"), Help: "Number of transactions received and sent by the wallet since the exporter started, counted when they are first seen in the mempool or in a block", ConstLabels: opts.ConstLabels,
+		}, labels),
+		Amounts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_wallet_amount_total"), Help: "Total amount in " + opts.AmountUnit() + " received and sent by the wallet since the exporter started, excluding fees", ConstLabels: opts.ConstLabels,
+		}, labels),
+
+		cursors: map[string]*walletCursor{},
+	}
+}
+
+// WalletActivityCollector counts wallet transactions from listsinceblock RPC responses, keeping a cursor for
+// each loaded wallet at the best block of its last collection. Transactions are not counted on the first
+// collection of a wallet, so that only activity while the exporter runs is counted. Transactions that are
+// confirmed again after a reorg may be counted twice
+type WalletActivityCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Wallets *WalletClients
+
+	Transactions *prometheus.CounterVec
+	Amounts      *prometheus.CounterVec
+
+	walletDisabled int32
+
+	mu      sync.Mutex
+	cursors map[string]*walletCursor
+}
+
+// walletCursor is the state of a wallet's last listsinceblock call
+type walletCursor struct {
+	// block is the lastblock of the response, from which the next call lists transactions
+	block string
+
+	// counted is the set of transactions in the response, by txid and category. Unconfirmed transactions are
+	// listed again when they are confirmed, and are only counted once
+	counted map[string]struct{}
+}
+
+// Inherit takes over the counters, wallet cursors and wallet clients of a previous WalletActivityCollector
+func (col *WalletActivityCollector) Inherit(previous prometheus.Collector) {
+	prev, ok := previous.(*WalletActivityCollector)
+	if !ok {
+		return
+	}
+
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+
+	col.Transactions, col.Amounts, col.cursors, col.Wallets = prev.Transactions, prev.Amounts, prev.cursors, prev.Wallets
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *WalletActivityCollector) Describe(out chan<- *prometheus.Desc) {
+	col.Transactions.Describe(out)
+	col.Amounts.Describe(out)
+}
+
+// Collect calls the listsinceblock RPC for each loaded wallet and counts transactions listed for the first time
+func (col *WalletActivityCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	wallets, err := col.ListWallets(col.Client)
+	if IsMethodNotFound(err) {
+		// Wallet RPCs are disabled, which is not an error, so the failure is only logged once
+		if atomic.CompareAndSwapInt32(&col.walletDisabled, 0, 1) {
+			col.Warn("Wallet RPCs are disabled, skipping wallet activity collection until they are enabled")
+		}
+
+		return
+	}
+
+	if err != nil {
+		LogRPCError(col.Logger, "listwallets", err)
+		return
+	}
+
+	atomic.StoreInt32(&col.walletDisabled, 0)
+	col.Wallets.Retain(wallets)
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	// Cursors of unloaded wallets are forgotten, and their activity is not counted if they are loaded again
+	cursors := make(map[string]*walletCursor, len(wallets))
+	for _, wallet := range wallets {
+		cursor, has := col.cursors[wallet]
+		if !has {
+			cursor = &walletCursor{block: chain.BestBlockHash}
+		}
+
+		result, err := col.listSinceBlock(wallet, cursor.block)
+		if IsWalletNotFound(err) {
+			col.Debug("Wallet was unloaded during collection", zap.String("wallet", wallet))
+			continue
+		}
+
+		if err != nil {
+			LogRPCError(col.Logger, "listsinceblock", err, zap.String("wallet", wallet))
+			cursors[wallet] = cursor
+			continue
+		}
+
+		LastCollection.Mark()
+
+		counted := map[string]struct{}{}
+		for _, tx := range result.Transactions {
+			if !walletActivityCategory(tx.Category) {
+				continue
+			}
+
+			// A transaction is listed once for each of its outputs, which are all new or all counted
+			key := tx.TxID + ":" + tx.Category
+			_, listed := counted[key]
+			counted[key] = struct{}{}
+
+			if _, seen := cursor.counted[key]; seen || !has {
+				continue
+			}
+
+			labels := []string{chain.Chain, wallet, tx.Category}
+			if col.Elements() {
+				labels = append(labels, tx.Asset)
+			}
+
+			if !listed {
+				col.Transactions.WithLabelValues(labels...).Inc()
+			}
+
+			// Amounts sent are negative
+			col.Amounts.WithLabelValues(labels...).Add(col.Amount(math.Abs(tx.Amount)))
+		}
+
+		cursors[wallet] = &walletCursor{block: result.LastBlock, counted: counted}
+	}
+
+	col.cursors = cursors
+
+	col.Transactions.Collect(out)
+	col.Amounts.Collect(out)
+}
+
+// walletActivityCategory returns true if a listsinceblock category is one of WalletActivityCategories
+func walletActivityCategory(category string) bool {
+	for _, counted := range WalletActivityCategories {
+		if category == counted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listSinceBlock calls listsinceblock on a wallet's RPC endpoint, including watch-only transactions, for the
+// blocks after block and the mempool
+func (col *WalletActivityCollector) listSinceBlock(wallet, block string) (*ListSinceBlockResult, error) {
+	client, err := col.Wallets.Client(wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations := 1
+	watchOnly := true
+
+	data, err := col.Receive(client.SendCmd(btcjson.NewListSinceBlockCmd(&block, &confirmations, &watchOnly)))
+	if err != nil {
+		return nil, err
+	}
+
+	var result ListSinceBlockResult
+	err = col.Decode("listsinceblock", data, &result)
+
+	return &result, err
+}
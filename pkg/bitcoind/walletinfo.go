@@ -0,0 +1,134 @@
+package bitcoind
+
+import (
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// GetWalletInfoResult unmarshals the properties of getwalletinfo responses that are exported
+type GetWalletInfoResult struct {
+	// LastProcessedBlock is the block up to which the wallet has scanned the chain. It is not reported by
+	// older nodes
+	LastProcessedBlock *struct {
+		Hash   string `json:"hash"`
+		Height int64  `json:"height"`
+	} `json:"lastprocessedblock"`
+}
+
+// NewWalletInfoDescriptors creates descriptors for collected wallet info metrics
+func NewWalletInfoDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_wallet_blocks_behind"), "Number of blocks between the node's best block and the last block processed by the wallet. A wallet that stays behind has stopped following the chain", []string{"chain", "wallet"}, opts.ConstLabels),
+	}
+}
+
+// NewWalletInfoCollector creates a new prometheus.Collector for getwalletinfo responses from each loaded wallet.
+// config is used to create clients for wallet RPC endpoints
+func NewWalletInfoCollector(client *rpcclient.Client, logger Logger, config rpcclient.ConnConfig, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &WalletInfoCollector{
+		Client:      client,
+		Logger:      logger,
+		Options:     opts,
+		Wallets:     NewWalletClients(config),
+		Descriptors: NewWalletInfoDescriptors(opts),
+	}
+}
+
+// WalletInfoCollector builds metrics from getwalletinfo RPC responses for each loaded wallet. Wallets are
+// listed on each collection, so series are added and removed as wallets are loaded and unloaded
+type WalletInfoCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Wallets *WalletClients
+
+	Descriptors []*prometheus.Desc
+
+	walletDisabled int32
+}
+
+// Inherit takes over the wallet clients of a previous WalletInfoCollector
+func (col *WalletInfoCollector) Inherit(previous prometheus.Collector) {
+	if prev, ok := previous.(*WalletInfoCollector); ok {
+		col.Wallets = prev.Wallets
+	}
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *WalletInfoCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the getwalletinfo RPC for each loaded wallet and builds metrics from its response properties
+func (col *WalletInfoCollector) Collect(out chan<- prometheus.Metric) {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		LogRPCError(col.Logger, "getblockchaininfo", err)
+		return
+	}
+
+	wallets, err := col.ListWallets(col.Client)
+	if IsMethodNotFound(err) {
+		// Wallet RPCs are disabled, which is not an error, so the failure is only logged once
+		if atomic.CompareAndSwapInt32(&col.walletDisabled, 0, 1) {
+			col.Warn("Wallet RPCs are disabled, skipping wallet info collection until they are enabled")
+		}
+
+		return
+	}
+
+	if err != nil {
+		LogRPCError(col.Logger, "listwallets", err)
+		return
+	}
+
+	atomic.StoreInt32(&col.walletDisabled, 0)
+	col.Wallets.Retain(wallets)
+
+	for _, wallet := range wallets {
+		info, err := col.walletInfo(wallet)
+		if IsWalletNotFound(err) {
+			col.Debug("Wallet was unloaded during collection", zap.String("wallet", wallet))
+			continue
+		}
+
+		if err != nil {
+			LogRPCError(col.Logger, "getwalletinfo", err, zap.String("wallet", wallet))
+			continue
+		}
+
+		LastCollection.Mark()
+
+		if info.LastProcessedBlock != nil {
+			metric, _ := prometheus.NewConstMetric(col.Descriptors[0], prometheus.GaugeValue, float64(int64(chain.Blocks)-info.LastProcessedBlock.Height), chain.Chain, wallet)
+			out <- metric
+		}
+	}
+}
+
+// walletInfo calls getwalletinfo on a wallet's RPC endpoint
+func (col *WalletInfoCollector) walletInfo(wallet string) (*GetWalletInfoResult, error) {
+	client, err := col.Wallets.Client(wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := col.Receive(client.SendCmd(btcjson.NewGetWalletInfoCmd()))
+	if err != nil {
+		return nil, err
+	}
+
+	var info GetWalletInfoResult
+	err = col.Decode("getwalletinfo", data, &info)
+
+	return &info, err
+}
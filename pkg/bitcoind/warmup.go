@@ -0,0 +1,95 @@
+package bitcoind
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// WarmupMessage returns bitcoind's warm-up status, e.g. "Loading block index…", if err is an RPC_IN_WARMUP error
+func WarmupMessage(err error) (string, bool) {
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCInWarmup {
+		return rpcErr.Message, true
+	}
+
+	return "", false
+}
+
+// LogRPCError logs and counts a failed RPC call. bitcoind rejects every call while it is warming up,
+// which is reported by the WarmupCollector, so those errors are only logged at debug level
+func LogRPCError(logger Logger, method string, err error, fields ...zap.Field) {
+	code := RPCErrorCode(err)
+	RPCErrors.WithLabelValues(method, code).Inc()
+
+	fields = append(fields, zap.String("code", code), zap.Error(err))
+
+	if _, warmup := WarmupMessage(err); warmup {
+		logger.Debug("RPC call "+method+" failed while bitcoind is warming up", fields...)
+		return
+	}
+
+	logger.Error("RPC call "+method+" failed", fields...)
+}
+
+// NewWarmupDescriptors creates descriptors for node warm-up metrics
+func NewWarmupDescriptors(opts Options) []*prometheus.Desc {
+	return []*prometheus.Desc{
+		prometheus.NewDesc(opts.Metric("bitcoind_warming_up"), "Whether bitcoind is rejecting RPC calls while it starts up or not", nil, opts.ConstLabels),
+		prometheus.NewDesc(opts.Metric("bitcoind_warming_up_info"), "bitcoind's warm-up status message", []string{"message"}, opts.ConstLabels),
+	}
+}
+
+// NewWarmupCollector creates a new prometheus.Collector for bitcoind's warm-up state
+func NewWarmupCollector(client *rpcclient.Client, logger Logger, options ...Option) prometheus.Collector {
+	opts := NewOptions(options...)
+
+	return &WarmupCollector{client, logger, opts, NewWarmupDescriptors(opts)}
+}
+
+// WarmupCollector builds metrics from ping RPC responses, which fail with RPC_IN_WARMUP (-28) while
+// bitcoind loads its block index and verifies recent blocks
+type WarmupCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Descriptors []*prometheus.Desc
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *WarmupCollector) Describe(out chan<- *prometheus.Desc) {
+	for _, desc := range col.Descriptors {
+		out <- desc
+	}
+}
+
+// Collect calls the ping RPC and builds metrics from its error
+func (col *WarmupCollector) Collect(out chan<- prometheus.Metric) {
+	err := col.Ping()
+	message, warmup := WarmupMessage(err)
+
+	if err != nil && !warmup {
+		LogRPCError(col.Logger, "ping", err)
+		return
+	}
+
+	// A node that is warming up is reachable, so the exporter is healthy
+	LastCollection.Mark()
+
+	var metric prometheus.Metric
+
+	if warmup {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[0], col.BoolType(), 1)
+		out <- metric
+
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[1], prometheus.GaugeValue, 1, message)
+		out <- metric
+	} else {
+		metric, _ = prometheus.NewConstMetric(col.Descriptors[0], col.BoolType(), 0)
+		out <- metric
+	}
+}
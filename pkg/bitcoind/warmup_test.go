@@ -0,0 +1,26 @@
+package bitcoind_test
+
+import (
+	"testing"
+
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoindtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWarmupCollector(t *testing.T) {
+	server := bitcoindtest.StartServer(t, bitcoind.Version24)
+	col := bitcoind.NewWarmupCollector(server.Client(t), bitcoindtest.Logger(t))
+
+	server.SetWarmup("Loading block index…")
+	families := bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_warming_up", nil, 1)
+	bitcoindtest.AssertValue(t, families, "bitcoind_warming_up_info", prometheus.Labels{"message": "Loading block index…"}, 1)
+
+	server.SetWarmup("")
+	families = bitcoindtest.Gather(t, col)
+
+	bitcoindtest.AssertValue(t, families, "bitcoind_warming_up", nil, 0)
+	bitcoindtest.AssertAbsent(t, families, "bitcoind_warming_up_info", nil)
+}
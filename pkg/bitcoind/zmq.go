@@ -0,0 +1,238 @@
+package bitcoind
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/jmanero/bitcoind-exporter/pkg/zmq"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ZMQReconnectDelay is the time to wait before reconnecting to a ZMQ publisher after an error
+var ZMQReconnectDelay = 5 * time.Second
+
+// SequenceEvents maps sequence notification labels to event label values
+var SequenceEvents = map[byte]string{
+	'C': "block_connected",
+	'D': "block_disconnected",
+	'A': "tx_added",
+	'R': "tx_removed",
+}
+
+// NewZMQCollector creates a collector for counters built from ZMQ notifications. Topics maps
+// publisher addresses to the topics to subscribe to at each. Run must be called to receive notifications
+func NewZMQCollector(client *rpcclient.Client, logger Logger, topics map[string][]string, options ...Option) *ZMQCollector {
+	opts := NewOptions(options...)
+
+	return &ZMQCollector{
+		Client:  client,
+		Logger:  logger,
+		Options: opts,
+		Topics:  topics,
+
+		Dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_zmq_dropped_messages_total"), Help: "Number of ZMQ notifications missed, detected from gaps in notification sequence numbers", ConstLabels: opts.ConstLabels,
+		}, []string{"chain", "topic"}),
+		Events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_zmq_sequence_events_total"), Help: "Number of block and mempool events received from the ZMQ sequence topic", ConstLabels: opts.ConstLabels,
+		}, []string{"chain", "event"}),
+		Removals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_mempool_removals_total"), Help: "Number of transactions removed from the mempool for reasons other than block inclusion. Removals immediately following an addition are size limit evictions or expiries, and removals immediately followed by an addition are inferred to be replacements", ConstLabels: opts.ConstLabels,
+		}, []string{"chain", "reason"}),
+		Replacements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_mempool_replacements_total"), Help: "Number of transactions added to the mempool that are inferred to have replaced one or more transactions, from removals immediately followed by the addition", ConstLabels: opts.ConstLabels,
+		}, []string{"chain"}),
+		Transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.Metric("bitcoind_transactions_seen_total"), Help: "Number of transactions accepted to the mempool, from tx_added events of the ZMQ sequence topic", ConstLabels: opts.ConstLabels,
+		}, []string{"chain"}),
+	}
+}
+
+// ZMQCollector builds counters from bitcoind's -zmqpub* notifications
+type ZMQCollector struct {
+	*rpcclient.Client
+	Logger
+	Options
+
+	Topics map[string][]string
+
+	Dropped      *prometheus.CounterVec
+	Events       *prometheus.CounterVec
+	Removals     *prometheus.CounterVec
+	Replacements *prometheus.CounterVec
+	Transactions *prometheus.CounterVec
+}
+
+// Describe returns the collector's metric descriptor set
+func (col *ZMQCollector) Describe(out chan<- *prometheus.Desc) {
+	col.Dropped.Describe(out)
+	col.Events.Describe(out)
+	col.Removals.Describe(out)
+	col.Replacements.Describe(out)
+	col.Transactions.Describe(out)
+}
+
+// Collect returns the current values of counters
+func (col *ZMQCollector) Collect(out chan<- prometheus.Metric) {
+	col.Dropped.Collect(out)
+	col.Events.Collect(out)
+	col.Removals.Collect(out)
+	col.Replacements.Collect(out)
+	col.Transactions.Collect(out)
+}
+
+// Run subscribes to each publisher until the context is canceled
+func (col *ZMQCollector) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for addr, topics := range col.Topics {
+		wg.Add(1)
+
+		go func(addr string, topics []string) {
+			defer wg.Done()
+
+			for {
+				err := col.subscribe(ctx, addr, topics)
+				if ctx.Err() != nil {
+					return
+				}
+
+				col.Error("ZMQ subscription failed", zap.String("addr", addr), zap.Strings("topics", topics), zap.Error(err))
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(ZMQReconnectDelay):
+				}
+			}
+		}(addr, topics)
+	}
+
+	wg.Wait()
+}
+
+// zmqState tracks notifications received from a single subscription
+type zmqState struct {
+	chain string
+
+	// Last notification sequence number by topic
+	sequence map[string]uint32
+
+	// Consecutive mempool removals that have not been classified yet, and whether they immediately
+	// followed an addition
+	removals        int
+	removalSequence uint64
+	evicted         bool
+
+	// Sequence number of the last event, if it was an addition
+	added         bool
+	addedSequence uint64
+}
+
+// subscribe receives notifications from a publisher until an error occurs
+func (col *ZMQCollector) subscribe(ctx context.Context, addr string, topics []string) error {
+	chain, err := col.BlockChainInfo(col.Client)
+	if err != nil {
+		return err
+	}
+
+	col.Info("Subscribing to ZMQ publisher", zap.String("addr", addr), zap.Strings("topics", topics))
+	sub, err := zmq.Dial(ctx, addr, topics...)
+	if err != nil {
+		return err
+	}
+
+	// Unblock Receive when the context is canceled
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+	defer sub.Close()
+
+	state := &zmqState{chain: chain.Chain, sequence: map[string]uint32{}}
+
+	for {
+		parts, err := sub.Receive()
+		if err != nil {
+			return err
+		}
+
+		// bitcoind notifications are [topic, body, 4-byte LE sequence number]
+		if len(parts) != 3 || len(parts[2]) != 4 {
+			col.Warn("Unexpected ZMQ notification", zap.Int("parts", len(parts)))
+			continue
+		}
+
+		topic := string(parts[0])
+		sequence := binary.LittleEndian.Uint32(parts[2])
+
+		if last, has := state.sequence[topic]; has && sequence-last > 1 {
+			col.Dropped.WithLabelValues(state.chain, topic).Add(float64(sequence - last - 1))
+		}
+		state.sequence[topic] = sequence
+
+		if topic == "sequence" {
+			col.handleSequence(state, parts[1])
+		}
+	}
+}
+
+// handleSequence counts a sequence notification: <32-byte hash><1-byte label>[<8-byte LE mempool sequence>]
+func (col *ZMQCollector) handleSequence(state *zmqState, body []byte) {
+	if len(body) < 33 {
+		return
+	}
+
+	label := body[32]
+
+	var sequence uint64
+	if len(body) >= 41 {
+		sequence = binary.LittleEndian.Uint64(body[33:41])
+	}
+
+	// When bitcoind replaces transactions, it removes each of them and then immediately adds the
+	// replacement. After adding a transaction, it expires old transactions and evicts transactions to
+	// keep the mempool within its size limit. Consecutive removals are classified together by the events
+	// around them. The events do not carry a reason, so replacements are inferred
+	if label == 'R' && state.removals > 0 && sequence == state.removalSequence+1 {
+		state.removals++
+		state.removalSequence = sequence
+	} else {
+		if state.removals > 0 {
+			switch {
+			case state.evicted:
+				col.Removals.WithLabelValues(state.chain, "evicted").Add(float64(state.removals))
+			case label == 'A' && sequence == state.removalSequence+1:
+				col.Removals.WithLabelValues(state.chain, "inferred_replaced").Add(float64(state.removals))
+				col.Replacements.WithLabelValues(state.chain).Inc()
+			default:
+				col.Removals.WithLabelValues(state.chain, "other").Add(float64(state.removals))
+			}
+
+			state.removals = 0
+		}
+
+		if label == 'R' {
+			state.removals = 1
+			state.removalSequence = sequence
+			state.evicted = state.added && sequence == state.addedSequence+1
+		}
+	}
+
+	state.added = label == 'A'
+	state.addedSequence = sequence
+
+	// bitcoind's hashtx topic publishes transactions again when their block connects, while the sequence
+	// topic adds each transaction once, when it is accepted to the mempool
+	if label == 'A' {
+		col.Transactions.WithLabelValues(state.chain).Inc()
+	}
+
+	if event, has := SequenceEvents[label]; has {
+		col.Events.WithLabelValues(state.chain, event).Inc()
+	}
+}
@@ -0,0 +1,96 @@
+package bitcoind
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zaptest"
+)
+
+// sequenceEvent builds a sequence notification body for a mempool event
+func sequenceEvent(label byte, sequence uint64) []byte {
+	body := make([]byte, 41)
+	body[32] = label
+	binary.LittleEndian.PutUint64(body[33:], sequence)
+
+	return body
+}
+
+func TestHandleSequenceRemovals(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   []byte
+		replaced float64
+		evicted  float64
+		other    float64
+		replaces float64
+	}{
+		{name: "single replacement", events: []byte("RA"), replaced: 1, replaces: 1},
+		{name: "replacement of several transactions", events: []byte("RRRA"), replaced: 3, replaces: 1},
+		{name: "removals followed by a block", events: []byte("RRC"), other: 2},
+		{name: "removals then a replacement", events: []byte("RCRRA"), replaced: 2, other: 1, replaces: 1},
+		{name: "additions only", events: []byte("AA")},
+		{name: "eviction followed by an addition", events: []byte("ARA"), evicted: 1},
+		{name: "eviction of several transactions followed by an addition", events: []byte("ARRRA"), evicted: 3},
+		{name: "eviction followed by a block", events: []byte("ARC"), evicted: 1},
+		{name: "eviction then a replacement", events: []byte("ARCRA"), evicted: 1, replaced: 1, replaces: 1},
+		{name: "removal after a block following an addition", events: []byte("ACRA"), replaced: 1, replaces: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			col := NewZMQCollector(nil, zaptest.NewLogger(t), nil)
+			state := &zmqState{chain: "main", sequence: map[string]uint32{}}
+
+			// Block events do not carry a mempool sequence number
+			var sequence uint64
+			for _, label := range test.events {
+				if label == 'C' || label == 'D' {
+					col.handleSequence(state, sequenceEvent(label, 0)[:33])
+					continue
+				}
+
+				sequence++
+				col.handleSequence(state, sequenceEvent(label, sequence))
+			}
+
+			if value := testutil.ToFloat64(col.Removals.WithLabelValues("main", "inferred_replaced")); value != test.replaced {
+				t.Errorf("replaced removals = %v, expected %v", value, test.replaced)
+			}
+
+			if value := testutil.ToFloat64(col.Removals.WithLabelValues("main", "evicted")); value != test.evicted {
+				t.Errorf("evicted removals = %v, expected %v", value, test.evicted)
+			}
+
+			if value := testutil.ToFloat64(col.Removals.WithLabelValues("main", "other")); value != test.other {
+				t.Errorf("other removals = %v, expected %v", value, test.other)
+			}
+
+			if value := testutil.ToFloat64(col.Replacements.WithLabelValues("main")); value != test.replaces {
+				t.Errorf("replacements = %v, expected %v", value, test.replaces)
+			}
+		})
+	}
+}
+
+func TestHandleSequenceTransactions(t *testing.T) {
+	col := NewZMQCollector(nil, zaptest.NewLogger(t), nil)
+	state := &zmqState{chain: "main", sequence: map[string]uint32{}}
+
+	col.handleSequence(state, sequenceEvent('A', 1))
+	col.handleSequence(state, sequenceEvent('R', 2))
+	col.handleSequence(state, sequenceEvent('A', 3))
+
+	// Transactions are removed from the mempool without a sequence event when their block connects
+	col.handleSequence(state, sequenceEvent('C', 0)[:33])
+
+	if value := testutil.ToFloat64(col.Transactions.WithLabelValues("main")); value != 2 {
+		t.Errorf("transactions seen = %v, expected 2", value)
+	}
+
+	// The removal directly follows an addition, so it is an eviction and the next addition is not a replacement
+	if value := testutil.ToFloat64(col.Replacements.WithLabelValues("main")); value != 0 {
+		t.Errorf("replacements = %v, expected 0", value)
+	}
+}
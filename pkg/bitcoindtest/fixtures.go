@@ -0,0 +1,245 @@
+package bitcoindtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/jmanero/bitcoind-exporter/pkg/bitcoind"
+)
+
+// Fixture values shared between responses
+const (
+	Chain     = "main"
+	Height    = 800000
+	BlockHash = "00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054"
+)
+
+// Object is a JSON object response
+type Object map[string]interface{}
+
+// Fixtures returns canned responses for the RPC methods called by collectors, in the format returned by a
+// Bitcoin Core version. RPCs and response fields added after version are left out. Every Server is created
+// with a new set of fixtures, which may be modified
+func Fixtures(version int32) map[string]interface{} {
+	mempool := Object{
+		"size":          5000,
+		"bytes":         2000000,
+		"usage":         9000000,
+		"total_fee":     0.5,
+		"maxmempool":    300000000,
+		"mempoolminfee": 0.00001,
+		"minrelaytxfee": 0.00001,
+	}
+
+	peers := []Object{
+		{
+			"id": 1, "addr": "203.0.113.1:8333", "network": "ipv4", "services": "0000000000000409", "relaytxes": true,
+			"lastsend": 1690000000, "lastrecv": 1690000001, "last_transaction": 1690000002, "last_block": 1690000003,
+			"bytessent": 1000, "bytesrecv": 2000, "conntime": 1689000000, "timeoffset": -1, "pingtime": 0.05, "minping": 0.04,
+			"version": 70016, "subver": "/Satoshi:" + Release(version) + "/", "inbound": false, "bip152_hb_to": true, "bip152_hb_from": false,
+			"startingheight": Height - 1000, "presynced_headers": -1, "synced_headers": Height, "synced_blocks": Height,
+			"connection_type": "outbound-full-relay", "minfeefilter": 0.00001, "addr_processed": 100, "addr_rate_limited": 2, "permissions": []string{},
+			"bytessent_per_msg": Object{"ping": 320, "inv": 680}, "bytesrecv_per_msg": Object{"pong": 320, "tx": 1680}, "addr_relay_enabled": true,
+		},
+		{
+			"id": 2, "addr": "198.51.100.2:50000", "network": "ipv4", "services": "0000000000000409", "relaytxes": true,
+			"lastsend": 1690000000, "lastrecv": 1690000001, "last_transaction": 0, "last_block": 0,
+			"bytessent": 500, "bytesrecv": 700, "conntime": 1689000000, "timeoffset": 3, "pingtime": 0.5, "minping": 0.3,
+			"version": 70016, "subver": "/Satoshi:24.0.1/", "inbound": true, "addnode": false, "bip152_hb_to": false, "bip152_hb_from": true,
+			"startingheight": Height - 10, "presynced_headers": -1, "synced_headers": Height, "synced_blocks": Height - 1,
+			"connection_type": "inbound", "minfeefilter": 0.00002, "addr_processed": 10, "addr_rate_limited": 0, "permissions": []string{"noban", "mempool"},
+			"bytessent_per_msg": Object{"ping": 160}, "bytesrecv_per_msg": Object{"pong": 160}, "addr_relay_enabled": false,
+		},
+	}
+
+	fixtures := map[string]interface{}{
+		"ping": nil,
+		"getblockchaininfo": Object{
+			"chain": Chain, "blocks": Height, "headers": Height + 1, "bestblockhash": BlockHash,
+			"difficulty": 5.5e13, "mediantime": 1690000000, "time": 1690000500, "verificationprogress": 0.9999,
+			"initialblockdownload": false, "chainwork": "00", "size_on_disk": 550000000000, "pruned": false,
+			"warnings": Warnings(version),
+		},
+		"getnetworkinfo": Object{
+			"version": version, "subversion": "/Satoshi:" + Release(version) + "/", "protocolversion": 70016,
+			"localservices": "0000000000000409", "localrelay": true, "timeoffset": 0, "networkactive": true,
+			"connections": len(peers), "relayfee": 0.00001, "incrementalfee": 0.00001,
+			"localaddresses": []Object{{"address": "192.0.2.1", "port": 8333, "score": 4}},
+			"networks": []Object{
+				{"name": "ipv4", "limited": false, "reachable": true, "proxy": "127.0.0.1:9050", "proxy_randomize_credentials": true},
+				{"name": "ipv6", "limited": false, "reachable": true, "proxy": "", "proxy_randomize_credentials": false},
+				{"name": "onion", "limited": true, "reachable": false, "proxy": "", "proxy_randomize_credentials": false},
+			},
+			"warnings": Warnings(version),
+		},
+		"getmempoolinfo": mempool,
+		"getpeerinfo":    peers,
+		"getblockheader": Object{
+			"hash": BlockHash, "confirmations": 1, "height": Height, "version": 536870912, "versionHex": "20000000",
+			"merkleroot": "00", "time": 1690000500, "mediantime": 1690000000, "nonce": 1, "bits": "17053894",
+			"difficulty": 5.5e13, "previousblockhash": "00",
+		},
+		"getrawmempool": Object{},
+		"getchaintips": []Object{
+			{"height": Height, "hash": BlockHash, "branchlen": 0, "status": "active"},
+			{"height": Height - 100, "hash": BlockHashAt(Height - 100)[:60] + "0001", "branchlen": 1, "status": "valid-fork"},
+		},
+		"getblockhash":  Handler(getBlockHash),
+		"getblockstats": Handler(getBlockStats),
+		"getblock":      Handler(getBlock),
+		"getblocktemplate": Object{
+			"version": 536870912, "previousblockhash": BlockHash, "height": Height + 1, "curtime": 1690000600,
+			"bits": "17053894", "coinbasevalue": 625002500, "weightlimit": 4000000, "sigoplimit": 80000,
+			"transactions": []Object{
+				{"txid": "01", "hash": "01", "data": "", "depends": []int{}, "fee": 2000, "sigops": 4, "weight": 800},
+				{"txid": "02", "hash": "02", "data": "", "depends": []int{1}, "fee": 500, "sigops": 4, "weight": 1000},
+			},
+		},
+		"estimatesmartfee": Object{"feerate": 0.0002, "blocks": 2},
+		"uptime":           3600,
+		"verifychain":      true,
+		"scantxoutset":     Object{"success": true, "txouts": 100, "height": Height, "bestblock": BlockHash, "unspents": []Object{}, "total_amount": 0},
+		"listwallets":      []string{""},
+		"listunspent":      []Object{}, "getconnectioncount": len(peers), "getwalletinfo": Object{"walletname": "", "txcount": 0},
+		"listsinceblock": Object{"transactions": []Object{}, "removed": []Object{}, "lastblock": BlockHash}, "getreceivedbyaddress": 0.5, "getreceivedbylabel": 1.25,
+	}
+
+	if version >= bitcoind.Version0_19 {
+		mempool["loaded"] = true
+	}
+
+	if version >= bitcoind.Version0_21 {
+		mempool["unbroadcastcount"] = 0
+		fixtures["getindexinfo"] = Object{"txindex": Object{"synced": true, "best_block_height": Height}}
+		fixtures["getnetworkinfo"].(Object)["localservicesnames"] = []string{"NETWORK", "WITNESS", "NETWORK_LIMITED"}
+	}
+
+	if version >= bitcoind.Version24 {
+		mempool["incrementalrelayfee"] = 0.00001
+		mempool["fullrbf"] = false
+	}
+
+	if version >= bitcoind.Version26 {
+		fixtures["getwalletinfo"].(Object)["lastprocessedblock"] = Object{"hash": BlockHashAt(Height - 2), "height": Height - 2}
+		fixtures["getchainstates"] = Object{
+			"headers":     Height + 1,
+			"chainstates": []Object{{"blocks": Height, "bestblockhash": BlockHash, "difficulty": 5.5e13, "verificationprogress": 0.9999, "coins_db_cache_bytes": 8388608, "coins_tip_cache_bytes": 438304768, "validated": true}},
+		}
+	}
+
+	// Fields that were added in later versions are removed from older versions' responses
+	for _, peer := range peers {
+		if version < bitcoind.Version0_19 {
+			delete(peer, "minping")
+		}
+
+		if version < bitcoind.Version0_21 {
+			delete(peer, "network")
+			delete(peer, "connection_type")
+			delete(peer, "last_transaction")
+			delete(peer, "last_block")
+		}
+
+		if version < bitcoind.Version22 {
+			delete(peer, "bip152_hb_to")
+			delete(peer, "bip152_hb_from")
+		}
+
+		if version < bitcoind.Version23 {
+			delete(peer, "addr_processed")
+			delete(peer, "addr_rate_limited")
+			delete(peer, "addr_relay_enabled")
+		}
+
+		if version < bitcoind.Version24 {
+			delete(peer, "presynced_headers")
+		}
+	}
+
+	return fixtures
+}
+
+// BlockHashAt returns the hash of the fixture block at a height, which is BlockHash at Height
+func BlockHashAt(height int64) string {
+	if height == Height {
+		return BlockHash
+	}
+
+	return fmt.Sprintf("%064x", height)
+}
+
+// getBlockHash answers getblockhash with BlockHashAt the requested height
+func getBlockHash(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	var height int64
+	if len(params) < 1 || json.Unmarshal(params[0], &height) != nil || height < 0 || height > Height {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Block height out of range")
+	}
+
+	return BlockHashAt(height), nil
+}
+
+// getBlockStats answers getblockstats for a block height with stats that vary by height
+func getBlockStats(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	var height int64
+	if len(params) < 1 || json.Unmarshal(params[0], &height) != nil || height < 0 || height > Height {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Target block height after current tip")
+	}
+
+	return Object{
+		"blockhash": BlockHashAt(height), "height": height, "txs": 3000 + height%1000,
+		"total_weight": 3990000 - height%100*10000, "total_size": 1600000, "totalfee": 20000000 + height%1000,
+		"subsidy": 625000000, "swtxs": 2500, "swtotal_size": 1200000, "swtotal_weight": 3000000,
+	}, nil
+}
+
+// getBlock answers getblock with a coinbase, a taproot spend and a segwit v0 spend, with prevouts at verbosity 3
+func getBlock(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	var hash string
+	if len(params) < 1 || json.Unmarshal(params[0], &hash) != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Block not found")
+	}
+
+	verbosity := 1
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &verbosity)
+	}
+
+	spend := func(txid, kind string) Object {
+		vin := Object{"txid": "00", "vout": 0}
+		if verbosity >= 3 {
+			vin["prevout"] = Object{"generated": false, "height": Height - 10, "value": 0.001, "scriptPubKey": Object{"type": kind}}
+		}
+
+		return Object{"txid": txid, "vin": []Object{vin}}
+	}
+
+	return Object{
+		"hash": hash, "height": Height, "weight": 3990000, "size": 1600000, "nTx": 3,
+		"tx": []Object{
+			{"txid": "00", "vin": []Object{{"coinbase": "00"}}},
+			spend("01", "witness_v1_taproot"),
+			spend("02", "witness_v0_keyhash"),
+		},
+	}, nil
+}
+
+// Release returns the release number of a version for user agents, e.g. 24.1.0, or 0.21.2 before v22
+func Release(version int32) string {
+	major, minor, patch := version/10000, version/100%100, version%100
+	if version < bitcoind.Version22 {
+		return "0." + strconv.Itoa(int(major)) + "." + strconv.Itoa(int(minor))
+	}
+
+	return strconv.Itoa(int(major)) + "." + strconv.Itoa(int(minor)) + "." + strconv.Itoa(int(patch))
+}
+
+// Warnings returns an empty warnings field, which is a string before v28.0.0 and a list after
+func Warnings(version int32) interface{} {
+	if version >= 280000 {
+		return []string{}
+	}
+
+	return ""
+}
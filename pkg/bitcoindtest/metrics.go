@@ -0,0 +1,110 @@
+package bitcoindtest
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// Logger creates a logger that writes to a test's log
+func Logger(t testing.TB) *zap.Logger {
+	return zaptest.NewLogger(t)
+}
+
+// Gather registers collectors with a new registry and gathers their metrics, failing the test if the
+// collectors are inconsistent or build invalid metrics
+func Gather(t testing.TB, collectors ...prometheus.Collector) []*dto.MetricFamily {
+	t.Helper()
+
+	registry := prometheus.NewPedanticRegistry()
+	for _, collector := range collectors {
+		err := registry.Register(collector)
+		if err != nil {
+			t.Fatalf("bitcoindtest: unable to register collector: %v", err)
+		}
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("bitcoindtest: unable to gather metrics: %v", err)
+	}
+
+	return families
+}
+
+// Find returns the metric of a family whose labels include every label in labels
+func Find(families []*dto.MetricFamily, name string, labels prometheus.Labels) (*dto.Metric, bool) {
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.Metric {
+			if matches(metric, labels) {
+				return metric, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Value returns the value of a gauge, counter or untyped metric whose labels include every label in labels
+func Value(families []*dto.MetricFamily, name string, labels prometheus.Labels) (float64, bool) {
+	metric, has := Find(families, name, labels)
+	switch {
+	case !has:
+		return 0, false
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue(), true
+	case metric.Counter != nil:
+		return metric.Counter.GetValue(), true
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue(), true
+	}
+
+	return 0, false
+}
+
+// AssertValue fails a test unless a gathered metric with labels has the expected value
+func AssertValue(t testing.TB, families []*dto.MetricFamily, name string, labels prometheus.Labels, expected float64) {
+	t.Helper()
+
+	value, has := Value(families, name, labels)
+	if !has {
+		t.Errorf("bitcoindtest: no metric %s%v", name, labels)
+		return
+	}
+
+	if value != expected {
+		t.Errorf("bitcoindtest: metric %s%v = %v, expected %v", name, labels, value, expected)
+	}
+}
+
+// AssertAbsent fails a test if any gathered metric of a family has labels
+func AssertAbsent(t testing.TB, families []*dto.MetricFamily, name string, labels prometheus.Labels) {
+	t.Helper()
+
+	if _, has := Find(families, name, labels); has {
+		t.Errorf("bitcoindtest: unexpected metric %s%v", name, labels)
+	}
+}
+
+// matches tests if a metric's labels include every label in labels
+func matches(metric *dto.Metric, labels prometheus.Labels) bool {
+	matched := 0
+	for _, pair := range metric.Label {
+		if value, has := labels[pair.GetName()]; has {
+			if value != pair.GetValue() {
+				return false
+			}
+
+			matched++
+		}
+	}
+
+	return matched == len(labels)
+}
@@ -0,0 +1,288 @@
+package bitcoindtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+)
+
+// Credentials accepted by Server
+const (
+	User = "bitcoindtest"
+	Pass = "bitcoindtest"
+)
+
+// Server is a fake bitcoind JSON-RPC and REST server that answers each method with a canned response.
+// Wallet RPCs are accepted at /wallet/<name> paths, and answered with the same responses as other RPCs
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]response
+	warmup    string
+	calls     map[string]int
+}
+
+type response struct {
+	result  json.RawMessage
+	err     *btcjson.RPCError
+	handler Handler
+}
+
+// Handler answers an RPC method with a result computed from the call's parameters, or with an error
+type Handler func(params []json.RawMessage) (interface{}, *btcjson.RPCError)
+
+// NewServer starts a Server answering with Fixtures for a Bitcoin Core version, e.g. bitcoind.Version24.
+// Responses can be replaced with Set and SetError. The caller must Close the server
+func NewServer(version int32) *Server {
+	server := &Server{responses: map[string]response{}, calls: map[string]int{}}
+	for method, result := range Fixtures(version) {
+		if handler, ok := result.(Handler); ok {
+			server.Handle(method, handler)
+			continue
+		}
+
+		server.Set(method, result)
+	}
+
+	server.Server = httptest.NewServer(server)
+	return server
+}
+
+// StartServer starts a Server for a test, which is closed when the test completes
+func StartServer(t testing.TB, version int32) *Server {
+	t.Helper()
+
+	server := NewServer(version)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Set replaces the result returned for an RPC method. The result is encoded as JSON. Methods listed by
+// the help RPC include every method with a response
+func (server *Server) Set(method string, result interface{}) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		panic("bitcoindtest: unable to encode result for " + method + ": " + err.Error())
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.responses[method] = response{result: data}
+}
+
+// SetError makes an RPC method return a JSON-RPC error
+func (server *Server) SetError(method string, code btcjson.RPCErrorCode, message string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.responses[method] = response{err: btcjson.NewRPCError(code, message)}
+}
+
+// Handle makes an RPC method answer with a handler, for responses that depend on the call's parameters
+func (server *Server) Handle(method string, handler Handler) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.responses[method] = response{handler: handler}
+}
+
+// Remove makes an RPC method return a method not found error, and removes it from help
+func (server *Server) Remove(method string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	delete(server.responses, method)
+}
+
+// SetWarmup makes every RPC method return bitcoind's warm-up error with a status message, or
+// clears it if message is empty
+func (server *Server) SetWarmup(message string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.warmup = message
+}
+
+// Calls returns the number of times that an RPC method has been called
+func (server *Server) Calls(method string) int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return server.calls[method]
+}
+
+// ConnConfig returns an rpcclient configuration for HTTP POST requests to the server
+func (server *Server) ConnConfig() rpcclient.ConnConfig {
+	return rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		User:         User,
+		Pass:         Pass,
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}
+}
+
+// Client creates an rpcclient for the server, which is shut down when the test completes
+func (server *Server) Client(t testing.TB) *rpcclient.Client {
+	t.Helper()
+
+	config := server.ConnConfig()
+
+	client, err := rpcclient.New(&config, nil)
+	if err != nil {
+		t.Fatalf("bitcoindtest: unable to create RPC client: %v", err)
+	}
+
+	t.Cleanup(client.Shutdown)
+	return client
+}
+
+// ServeHTTP answers JSON-RPC POST requests and REST GET requests
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/rest/") {
+		server.serveREST(w, r)
+		return
+	}
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != User || pass != Pass {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := server.call(request.Method)
+	if res.handler != nil {
+		res = handle(res.handler, request.Params)
+	}
+
+	// bitcoind answers errors with HTTP status codes as well as JSON-RPC error objects
+	status := http.StatusOK
+	switch {
+	case res.err == nil:
+	case res.err.Code == btcjson.ErrRPCMethodNotFound.Code:
+		status = http.StatusNotFound
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	result := res.result
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Result json.RawMessage   `json:"result"`
+		Error  *btcjson.RPCError `json:"error"`
+		ID     json.RawMessage   `json:"id"`
+	}{result, res.err, request.ID})
+}
+
+// handle calls a Handler and encodes its result
+func handle(handler Handler, params []json.RawMessage) response {
+	result, rpcErr := handler(params)
+	if rpcErr != nil {
+		return response{err: rpcErr}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return response{err: btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, err.Error())}
+	}
+
+	return response{result: data}
+}
+
+// call counts a call to an RPC method and returns its response
+func (server *Server) call(method string) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.calls[method]++
+
+	if server.warmup != "" {
+		return response{err: btcjson.NewRPCError(btcjson.ErrRPCInWarmup, server.warmup)}
+	}
+
+	if method == "help" {
+		return response{result: server.help()}
+	}
+
+	res, has := server.responses[method]
+	if !has {
+		return response{err: btcjson.ErrRPCMethodNotFound}
+	}
+
+	return res
+}
+
+// help lists the methods that have responses in the format of bitcoind's help RPC
+func (server *Server) help() json.RawMessage {
+	methods := []string{"help"}
+	for method := range server.responses {
+		methods = append(methods, method)
+	}
+
+	sort.Strings(methods)
+
+	data, _ := json.Marshal("== Methods ==\n" + strings.Join(methods, "\n") + "\n")
+	return data
+}
+
+// serveREST answers the REST endpoints with the responses of the equivalent RPC methods
+func (server *Server) serveREST(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/rest/")
+
+	var method string
+	switch {
+	case path == "chaininfo.json":
+		method = "getblockchaininfo"
+	case path == "mempool/info.json":
+		method = "getmempoolinfo"
+	case strings.HasPrefix(path, "headers/") && strings.HasSuffix(path, ".json"):
+		method = "getblockheader"
+	default:
+		http.Error(w, "Invalid URI format", http.StatusNotFound)
+		return
+	}
+
+	// REST requests have no parameters for handlers
+	res := server.call(method)
+	if res.handler != nil {
+		res = handle(res.handler, nil)
+	}
+
+	if res.err == nil && method == "getblockheader" {
+		res.result = json.RawMessage("[" + string(res.result) + "]")
+	}
+
+	if res.err != nil {
+		http.Error(w, res.err.Message, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(res.result)
+}
@@ -0,0 +1,123 @@
+package logfile
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Writer appends to a log file, rotating it when a write would grow it beyond MaxSize. Rotated files are
+// renamed with numbered suffixes, path.1 being the most recent, and files beyond MaxBackups are removed
+type Writer struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens or creates a log file for appending. Rotation is disabled if maxSize is zero
+func Open(path string, maxSize int64, maxBackups int) (*Writer, error) {
+	writer := &Writer{Path: path, MaxSize: maxSize, MaxBackups: maxBackups}
+
+	err := writer.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (writer *Writer) open() error {
+	file, err := os.OpenFile(writer.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.file = file
+	writer.size = info.Size()
+
+	return nil
+}
+
+// Write appends data to the log file, rotating it first if it would exceed MaxSize. A single write larger
+// than MaxSize is written to a new file rather than split
+func (writer *Writer) Write(data []byte) (int, error) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.MaxSize > 0 && writer.size > 0 && writer.size+int64(len(data)) > writer.MaxSize {
+		err := writer.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := writer.file.Write(data)
+	writer.size += int64(n)
+
+	return n, err
+}
+
+// Sync flushes the log file to disk
+func (writer *Writer) Sync() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	return writer.file.Sync()
+}
+
+// Close closes the log file
+func (writer *Writer) Close() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	return writer.file.Close()
+}
+
+// rotate renames the log file and its backups to the next suffixes, removing the oldest, and opens a new file.
+// The caller must hold writer.mu
+func (writer *Writer) rotate() error {
+	err := writer.file.Close()
+	if err != nil {
+		return err
+	}
+
+	if writer.MaxBackups < 1 {
+		// Without backups, the log file is truncated
+		err = os.Remove(writer.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return writer.open()
+	}
+
+	os.Remove(writer.backup(writer.MaxBackups))
+	for n := writer.MaxBackups - 1; n > 0; n-- {
+		err = os.Rename(writer.backup(n), writer.backup(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.Rename(writer.Path, writer.backup(1))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return writer.open()
+}
+
+// backup returns the path of a numbered backup file
+func (writer *Writer) backup(n int) string {
+	return writer.Path + "." + strconv.Itoa(n)
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Webhook payload formats
+const (
+	// FormatJSON posts a message's fields as a JSON object
+	FormatJSON = "json"
+
+	// FormatSlack posts a message's text in the payload of Slack incoming webhooks, which is also accepted by
+	// Mattermost, Rocket.Chat and Discord's /slack endpoints
+	FormatSlack = "slack"
+)
+
+// Webhook posts messages to an HTTP endpoint
+type Webhook struct {
+	URL    string
+	Format string
+	HTTP   *http.Client
+}
+
+// NewWebhook creates a Webhook for a URL and one of the payload formats
+func NewWebhook(url, format string) *Webhook {
+	return &Webhook{url, format, &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name returns the webhook's host, for logs. Webhook URLs often contain a secret token in their path
+func (hook *Webhook) Name() string {
+	parsed, err := url.Parse(hook.URL)
+	if err != nil {
+		return hook.Format
+	}
+
+	return hook.Format + "+" + parsed.Host
+}
+
+// Send posts a message, either its fields or its text depending on the webhook's format
+func (hook *Webhook) Send(ctx context.Context, text string, fields interface{}) error {
+	body := fields
+	if hook.Format == FormatSlack {
+		body = map[string]string{"text": text}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := hook.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("webhook: %s returned %s: %s", hook.Name(), res.Status, bytes.TrimSpace(message))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client posts OTLP/HTTP requests with JSON encoding to a collector. See
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+type Client struct {
+	// Endpoint is the collector's base URL, e.g. http://127.0.0.1:4318
+	Endpoint string
+	Headers  map[string]string
+	HTTP     *http.Client
+}
+
+// NewClient creates a Client for a collector's base URL
+func NewClient(endpoint string, headers map[string]string) *Client {
+	return &Client{strings.TrimSuffix(endpoint, "/"), headers, &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Post encodes a request body and sends it to a signal path, e.g. /v1/traces
+func (client *Client) Post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range client.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := client.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("otlp: %s returned %s: %s", path, res.Status, bytes.TrimSpace(message))
+	}
+
+	return nil
+}
+
+// KeyValue is an OTLP attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is an OTLP attribute value. Only string values are used by the exporter
+type AnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// String creates a string attribute
+func String(key, value string) KeyValue {
+	return KeyValue{key, AnyValue{value}}
+}
+
+// Resource describes the entity producing telemetry
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// Scope describes the instrumentation library producing telemetry
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// NewResource creates a Resource for a service
+func NewResource(service string, attributes ...KeyValue) Resource {
+	return Resource{append([]KeyValue{String("service.name", service)}, attributes...)}
+}
@@ -0,0 +1,36 @@
+package otlp
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TracedCollector wraps a prometheus.Collector to record a span for each collection
+type TracedCollector struct {
+	prometheus.Collector
+	*Tracer
+
+	Name   string
+	Parent *Span
+}
+
+// Collect records a child span of Parent around the wrapped collector's Collect
+func (col *TracedCollector) Collect(out chan<- prometheus.Metric) {
+	span := col.Start("collect "+col.Name, SpanKindInternal, col.Parent, String("collector", col.Name))
+	defer span.Finish()
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		col.Collector.Collect(metrics)
+		close(metrics)
+	}()
+
+	var count int
+	for metric := range metrics {
+		count++
+		out <- metric
+	}
+
+	span.SetAttribute("metrics", strconv.Itoa(count))
+}
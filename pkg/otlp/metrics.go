@@ -0,0 +1,248 @@
+package otlp
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Aggregation temporality of cumulative sums and histograms
+const AggregationTemporalityCumulative = 2
+
+// MetricsExporter gathers metrics from a prometheus.Gatherer and pushes them to a collector
+type MetricsExporter struct {
+	*Client
+	prometheus.Gatherer
+
+	Resource Resource
+	Scope    Scope
+
+	// Start is reported as the start time of cumulative data points
+	Start time.Time
+}
+
+// NewMetricsExporter creates a MetricsExporter that pushes metrics from gatherer through client
+func NewMetricsExporter(client *Client, gatherer prometheus.Gatherer, resource Resource) *MetricsExporter {
+	return &MetricsExporter{client, gatherer, resource, Scope{Name: "github.com/jmanero/bitcoind-exporter"}, time.Now()}
+}
+
+// Metric is an OTLP metric with exactly one of its data fields set
+type Metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Gauge       *Gauge     `json:"gauge,omitempty"`
+	Sum         *Sum       `json:"sum,omitempty"`
+	Histogram   *Histogram `json:"histogram,omitempty"`
+	Summary     *Summary   `json:"summary,omitempty"`
+}
+
+// Gauge is an OTLP gauge
+type Gauge struct {
+	DataPoints []NumberDataPoint `json:"dataPoints"`
+}
+
+// Sum is an OTLP sum
+type Sum struct {
+	DataPoints             []NumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+// NumberDataPoint is an OTLP gauge or sum data point
+type NumberDataPoint struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+	Start      string     `json:"startTimeUnixNano,omitempty"`
+	Time       string     `json:"timeUnixNano"`
+	AsDouble   float64    `json:"asDouble"`
+}
+
+// Histogram is an OTLP explicit bucket histogram
+type Histogram struct {
+	DataPoints             []HistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+// HistogramDataPoint is an OTLP explicit bucket histogram data point. Bucket counts are not cumulative
+type HistogramDataPoint struct {
+	Attributes     []KeyValue `json:"attributes,omitempty"`
+	Start          string     `json:"startTimeUnixNano"`
+	Time           string     `json:"timeUnixNano"`
+	Count          string     `json:"count"`
+	Sum            float64    `json:"sum"`
+	BucketCounts   []string   `json:"bucketCounts"`
+	ExplicitBounds []float64  `json:"explicitBounds"`
+}
+
+// Summary is an OTLP summary
+type Summary struct {
+	DataPoints []SummaryDataPoint `json:"dataPoints"`
+}
+
+// SummaryDataPoint is an OTLP summary data point
+type SummaryDataPoint struct {
+	Attributes     []KeyValue      `json:"attributes,omitempty"`
+	Start          string          `json:"startTimeUnixNano"`
+	Time           string          `json:"timeUnixNano"`
+	Count          string          `json:"count"`
+	Sum            float64         `json:"sum"`
+	QuantileValues []QuantileValue `json:"quantileValues"`
+}
+
+// QuantileValue is an OTLP summary quantile
+type QuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+func attributes(labels []*dto.LabelPair) []KeyValue {
+	values := make([]KeyValue, len(labels))
+	for i, label := range labels {
+		values[i] = String(label.GetName(), label.GetValue())
+	}
+
+	return values
+}
+
+// finite drops NaN and infinite values, which can not be encoded as JSON numbers
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// Convert builds OTLP metrics from gathered metric families. Untyped metrics are exported as gauges
+func (exp *MetricsExporter) Convert(families []*dto.MetricFamily, now time.Time) []Metric {
+	start := unixNano(exp.Start)
+	ts := unixNano(now)
+
+	metrics := make([]Metric, 0, len(families))
+
+	for _, family := range families {
+		metric := Metric{Name: family.GetName(), Description: family.GetHelp()}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			metric.Sum = &Sum{AggregationTemporality: AggregationTemporalityCumulative, IsMonotonic: true}
+
+			for _, m := range family.Metric {
+				if value := m.GetCounter().GetValue(); finite(value) {
+					metric.Sum.DataPoints = append(metric.Sum.DataPoints, NumberDataPoint{attributes(m.Label), start, ts, value})
+				}
+			}
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			metric.Gauge = &Gauge{}
+
+			for _, m := range family.Metric {
+				value := m.GetGauge().GetValue()
+				if m.Untyped != nil {
+					value = m.GetUntyped().GetValue()
+				}
+
+				if finite(value) {
+					metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, NumberDataPoint{attributes(m.Label), "", ts, value})
+				}
+			}
+
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			metric.Histogram = &Histogram{AggregationTemporality: AggregationTemporalityCumulative}
+
+			for _, m := range family.Metric {
+				hist := m.GetHistogram()
+				point := HistogramDataPoint{
+					Attributes: attributes(m.Label),
+					Start:      start,
+					Time:       ts,
+					Count:      strconv.FormatUint(hist.GetSampleCount(), 10),
+					Sum:        hist.GetSampleSum(),
+				}
+
+				// Prometheus buckets are cumulative, with an implicit +Inf bucket
+				var previous uint64
+				for _, bucket := range hist.Bucket {
+					if math.IsInf(bucket.GetUpperBound(), 1) {
+						continue
+					}
+
+					point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+					point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+					previous = bucket.GetCumulativeCount()
+				}
+
+				point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(hist.GetSampleCount()-previous, 10))
+				metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, point)
+			}
+
+		case dto.MetricType_SUMMARY:
+			metric.Summary = &Summary{}
+
+			for _, m := range family.Metric {
+				summary := m.GetSummary()
+				point := SummaryDataPoint{
+					Attributes: attributes(m.Label),
+					Start:      start,
+					Time:       ts,
+					Count:      strconv.FormatUint(summary.GetSampleCount(), 10),
+					Sum:        summary.GetSampleSum(),
+				}
+
+				for _, q := range summary.Quantile {
+					if finite(q.GetValue()) {
+						point.QuantileValues = append(point.QuantileValues, QuantileValue{q.GetQuantile(), q.GetValue()})
+					}
+				}
+
+				metric.Summary.DataPoints = append(metric.Summary.DataPoints, point)
+			}
+
+		default:
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// Push gathers metrics and exports them. Gather errors are returned after exporting any gathered metrics
+func (exp *MetricsExporter) Push(ctx context.Context) error {
+	families, gatherErr := exp.Gather()
+
+	type scopeMetrics struct {
+		Scope   Scope    `json:"scope"`
+		Metrics []Metric `json:"metrics"`
+	}
+
+	type resourceMetrics struct {
+		Resource     Resource       `json:"resource"`
+		ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+	}
+
+	err := exp.Post(ctx, "/v1/metrics", map[string][]resourceMetrics{
+		"resourceMetrics": {{exp.Resource, []scopeMetrics{{exp.Scope, exp.Convert(families, time.Now())}}}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return gatherErr
+}
+
+// Run pushes metrics every interval until the context is canceled. Errors are passed to onError
+func (exp *MetricsExporter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if err := exp.Push(ctx); err != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package otlp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Span kinds and status codes used by the exporter
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+
+	StatusCodeOk    = 1
+	StatusCodeError = 2
+)
+
+// Tracer buffers ended spans and exports them in batches
+type Tracer struct {
+	*Client
+	Resource Resource
+	Scope    Scope
+
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewTracer creates a Tracer that exports spans through client
+func NewTracer(client *Client, resource Resource) *Tracer {
+	return &Tracer{Client: client, Resource: resource, Scope: Scope{Name: "github.com/jmanero/bitcoind-exporter"}}
+}
+
+// Span is an OTLP span. Attributes should only be changed before calling End
+type Span struct {
+	tracer *Tracer
+
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+	Status       Status     `json:"status"`
+}
+
+// Status is an OTLP span status
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Start a span. The span is a child of parent if it is not nil, or otherwise starts a new trace
+func (tracer *Tracer) Start(name string, kind int, parent *Span, attributes ...KeyValue) *Span {
+	span := &Span{
+		tracer:     tracer,
+		SpanID:     randomID(8),
+		Name:       name,
+		Kind:       kind,
+		Start:      unixNano(time.Now()),
+		Attributes: attributes,
+	}
+
+	if parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = randomID(16)
+	}
+
+	return span
+}
+
+// SetAttribute adds a string attribute to the span
+func (span *Span) SetAttribute(key, value string) {
+	span.Attributes = append(span.Attributes, String(key, value))
+}
+
+// SetError marks the span as failed
+func (span *Span) SetError(message string) {
+	span.Status = Status{StatusCodeError, message}
+}
+
+// Finish ends the span and queues it for export
+func (span *Span) Finish() {
+	span.End = unixNano(time.Now())
+
+	span.tracer.mu.Lock()
+	defer span.tracer.mu.Unlock()
+
+	span.tracer.spans = append(span.tracer.spans, span)
+}
+
+// Flush exports buffered spans
+func (tracer *Tracer) Flush(ctx context.Context) error {
+	tracer.mu.Lock()
+	spans := tracer.spans
+	tracer.spans = nil
+	tracer.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	type scopeSpans struct {
+		Scope Scope   `json:"scope"`
+		Spans []*Span `json:"spans"`
+	}
+
+	type resourceSpans struct {
+		Resource   Resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	return tracer.Post(ctx, "/v1/traces", map[string][]resourceSpans{
+		"resourceSpans": {{tracer.Resource, []scopeSpans{{tracer.Scope, spans}}}},
+	})
+}
+
+// Run flushes buffered spans every interval until the context is canceled, and then flushes once
+// more. Export errors are passed to onError
+func (tracer *Tracer) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			// Export the final batch after the parent context has been canceled
+			final, done := context.WithTimeout(context.Background(), 5*time.Second)
+			defer done()
+
+			if err := tracer.Flush(final); err != nil {
+				onError(err)
+			}
+
+			return
+		case <-time.After(interval):
+		}
+
+		if err := tracer.Flush(ctx); err != nil {
+			onError(err)
+		}
+	}
+}
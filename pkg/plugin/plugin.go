@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+var (
+	upDesc       = prometheus.NewDesc("bitcoind_exporter_plugin_up", "Whether the last run of the plugin succeeded and its output was parsed", []string{"plugin"}, nil)
+	durationDesc = prometheus.NewDesc("bitcoind_exporter_plugin_duration_seconds", "Time taken by the last run of the plugin", []string{"plugin"}, nil)
+	failuresDesc = prometheus.NewDesc("bitcoind_exporter_plugin_failures_total", "Number of plugin runs that failed or produced output that could not be parsed", []string{"plugin"}, nil)
+)
+
+// Sample is a metric in a plugin's JSON output. Type is one of counter, gauge or untyped, the default
+type Sample struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// Collector runs an executable on each collection, and exports the metrics that it prints to stdout in
+// the text exposition format or as a JSON array of Samples
+type Collector struct {
+	*zap.Logger
+
+	Name    string
+	Command []string
+	Timeout time.Duration
+
+	failures int64
+}
+
+// NewCollector creates a Collector that runs command, which is an executable path followed by its arguments
+func NewCollector(logger *zap.Logger, name string, command []string, timeout time.Duration) *Collector {
+	return &Collector{Logger: logger, Name: name, Command: command, Timeout: timeout}
+}
+
+// Describe sends no descriptors, making Collector an unchecked collector, because plugin metrics are only known after a run
+func (col *Collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect runs the plugin and sends its metrics, followed by the plugin's health metrics
+func (col *Collector) Collect(out chan<- prometheus.Metric) {
+	start := time.Now()
+	families, err := col.run()
+	duration := time.Since(start)
+
+	if err != nil {
+		atomic.AddInt64(&col.failures, 1)
+		col.Error("Plugin failed", zap.String("plugin", col.Name), zap.Error(err))
+	}
+
+	for _, family := range families {
+		for _, m := range family.Metric {
+			metric, err := constMetric(family, m)
+			if err != nil {
+				col.Warn("Dropping invalid plugin metric", zap.String("plugin", col.Name), zap.String("metric", family.GetName()), zap.Error(err))
+				continue
+			}
+
+			out <- metric
+		}
+	}
+
+	metric, _ := prometheus.NewConstMetric(durationDesc, prometheus.GaugeValue, duration.Seconds(), col.Name)
+	out <- metric
+
+	metric, _ = prometheus.NewConstMetric(failuresDesc, prometheus.CounterValue, float64(atomic.LoadInt64(&col.failures)), col.Name)
+	out <- metric
+
+	if err != nil {
+		metric, _ = prometheus.NewConstMetric(upDesc, prometheus.GaugeValue, 0, col.Name)
+	} else {
+		metric, _ = prometheus.NewConstMetric(upDesc, prometheus.GaugeValue, 1, col.Name)
+	}
+	out <- metric
+}
+
+// run executes the plugin and parses its output
+func (col *Collector) run() ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), col.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, col.Command[0], col.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait also waits for stdout to be closed, which is held open by any children that outlive a killed plugin
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+	}
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %s", col.Timeout)
+	}
+
+	if stderr.Len() > 0 {
+		col.Debug("Plugin wrote to stderr", zap.String("plugin", col.Name), zap.String("stderr", strings.TrimSpace(stderr.String())))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(stdout.Bytes())
+}
+
+// Parse reads metric families from a JSON array of Samples, or from the text exposition format otherwise
+func Parse(data []byte) ([]*dto.MetricFamily, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return parseJSON(trimmed)
+	}
+
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, family := range parsed {
+		families = append(families, family)
+	}
+
+	return families, nil
+}
+
+func parseJSON(data []byte) ([]*dto.MetricFamily, error) {
+	var samples []Sample
+
+	err := json.Unmarshal(data, &samples)
+	if err != nil {
+		return nil, err
+	}
+
+	var families []*dto.MetricFamily
+	byName := map[string]*dto.MetricFamily{}
+
+	for _, sample := range samples {
+		var kind dto.MetricType
+		var m dto.Metric
+
+		value := sample.Value
+
+		switch sample.Type {
+		case "counter":
+			kind = dto.MetricType_COUNTER
+			m.Counter = &dto.Counter{Value: &value}
+		case "gauge":
+			kind = dto.MetricType_GAUGE
+			m.Gauge = &dto.Gauge{Value: &value}
+		case "", "untyped":
+			kind = dto.MetricType_UNTYPED
+			m.Untyped = &dto.Untyped{Value: &value}
+		default:
+			return nil, fmt.Errorf("metric %s has unsupported type %q", sample.Name, sample.Type)
+		}
+
+		for name, value := range sample.Labels {
+			name, value := name, value
+			m.Label = append(m.Label, &dto.LabelPair{Name: &name, Value: &value})
+		}
+
+		family, has := byName[sample.Name]
+		if !has {
+			name, help := sample.Name, sample.Help
+			family = &dto.MetricFamily{Name: &name, Help: &help, Type: &kind}
+
+			byName[name] = family
+			families = append(families, family)
+		}
+
+		if family.GetType() != kind {
+			return nil, fmt.Errorf("metric %s has conflicting types", sample.Name)
+		}
+
+		family.Metric = append(family.Metric, &m)
+	}
+
+	return families, nil
+}
+
+// constMetric converts a parsed metric to a prometheus.Metric
+func constMetric(family *dto.MetricFamily, m *dto.Metric) (prometheus.Metric, error) {
+	labels := make([]*dto.LabelPair, len(m.Label))
+	copy(labels, m.Label)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+
+	names := make([]string, len(labels))
+	values := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.GetName()
+		values[i] = label.GetValue()
+	}
+
+	desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), names, nil)
+
+	var metric prometheus.Metric
+	var err error
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), values...)
+	case dto.MetricType_GAUGE:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), values...)
+	case dto.MetricType_UNTYPED:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), values...)
+	case dto.MetricType_HISTOGRAM:
+		hist := m.GetHistogram()
+		buckets := map[float64]uint64{}
+		for _, bucket := range hist.Bucket {
+			// The +Inf bucket is implied by the sample count
+			if !math.IsInf(bucket.GetUpperBound(), 1) {
+				buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+			}
+		}
+
+		metric, err = prometheus.NewConstHistogram(desc, hist.GetSampleCount(), hist.GetSampleSum(), buckets, values...)
+	case dto.MetricType_SUMMARY:
+		summary := m.GetSummary()
+		quantiles := map[float64]float64{}
+		for _, q := range summary.Quantile {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+
+		metric, err = prometheus.NewConstSummary(desc, summary.GetSampleCount(), summary.GetSampleSum(), quantiles, values...)
+	default:
+		return nil, fmt.Errorf("unsupported metric type %s", family.GetType())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if m.TimestampMs != nil {
+		metric = prometheus.NewMetricWithTimestamp(time.UnixMilli(m.GetTimestampMs()), metric)
+	}
+
+	return metric, nil
+}
@@ -0,0 +1,130 @@
+package redact
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Placeholder replaces redacted secrets
+const Placeholder = "[REDACTED]"
+
+// patterns match secrets that are redacted whether or not they have been added to Secrets: passwords in URL
+// user info, HTTP Authorization header values, and bitcoind RPC cookies
+var patterns = []struct {
+	match   *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]*:)[^@/\s]*@`), "${1}" + Placeholder + "@"},
+	{regexp.MustCompile(`(?i)((?:proxy-)?authorization:?\s*(?:basic|bearer)?\s*)[a-zA-Z0-9+/=._~-]{8,}`), "${1}" + Placeholder},
+	{regexp.MustCompile(`__cookie__:[0-9a-fA-F]+`), "__cookie__:" + Placeholder},
+}
+
+// MinLength is the shortest secret that is redacted, as shorter values would be replaced within unrelated words.
+// Credentials in URLs and headers are still redacted by pattern
+const MinLength = 4
+
+// Secrets is a set of values that are replaced with Placeholder in redacted text
+type Secrets struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// Add adds values to the set. Values shorter than MinLength are ignored
+func (secrets *Secrets) Add(values ...string) {
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) >= MinLength {
+			secrets.values = append(secrets.values, value)
+		}
+	}
+}
+
+// Redact replaces secrets in text, and any URL passwords, authorization headers and RPC cookies
+func (secrets *Secrets) Redact(text string) string {
+	secrets.mu.RLock()
+	for _, value := range secrets.values {
+		text = strings.ReplaceAll(text, value, Placeholder)
+	}
+	secrets.mu.RUnlock()
+
+	for _, pattern := range patterns {
+		text = pattern.match.ReplaceAllString(text, pattern.replace)
+	}
+
+	return text
+}
+
+// NewCore wraps a zapcore.Core, redacting secrets from log messages and from string, error and stringer fields
+func NewCore(core zapcore.Core, secrets *Secrets) zapcore.Core {
+	return &redactCore{core, secrets}
+}
+
+type redactCore struct {
+	zapcore.Core
+	secrets *Secrets
+}
+
+func (core *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{core.Core.With(core.fields(fields)), core.secrets}
+}
+
+func (core *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+func (core *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = core.secrets.Redact(entry.Message)
+	return core.Core.Write(entry, core.fields(fields))
+}
+
+// fields returns a copy of fields with secrets redacted from string values. Error and stringer fields are
+// converted to strings, so that secrets in their messages are redacted
+func (core *redactCore) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = core.secrets.Redact(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok && err != nil {
+				field = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: core.secrets.Redact(stringify(field.Interface, err.Error))}
+			}
+		case zapcore.StringerType:
+			if stringer, ok := field.Interface.(fmt.Stringer); ok && stringer != nil {
+				field = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: core.secrets.Redact(stringify(field.Interface, stringer.String))}
+			}
+		}
+
+		redacted[i] = field
+	}
+
+	return redacted
+}
+
+// stringify calls an Error or String method of value. Like zap's own encoders, it recovers from panics, and
+// writes <nil> for typed nil pointers whose methods do not handle nil receivers
+func stringify(value interface{}, method func() string) (text string) {
+	defer func() {
+		if err := recover(); err != nil {
+			if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+				text = "<nil>"
+				return
+			}
+
+			text = fmt.Sprintf("PANIC=%v", err)
+		}
+	}()
+
+	return method()
+}
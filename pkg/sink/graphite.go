@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Graphite sends samples to a Carbon plaintext listener over TCP. Labels are sent as Graphite 1.1 tags
+type Graphite struct {
+	Addr    string
+	Prefix  string
+	Timeout time.Duration
+}
+
+// NewGraphite creates a Graphite sink for a host:port address. Metric names are prefixed with prefix, if set
+func NewGraphite(addr, prefix string) *Graphite {
+	return &Graphite{addr, prefix, 10 * time.Second}
+}
+
+// Tag values may not contain the tag and value separators or whitespace
+var graphiteReplacer = strings.NewReplacer(";", "_", "=", "_", "~", "_", "!", "_", "^", "_", " ", "_", "\t", "_", "\n", "_")
+
+// Flush writes samples as `name;tag=value value timestamp` lines
+func (sink *Graphite) Flush(samples []Sample, now time.Time) error {
+	conn, err := net.DialTimeout("tcp", sink.Addr, sink.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(sink.Timeout))
+
+	timestamp := " " + strconv.FormatInt(now.Unix(), 10) + "\n"
+	out := bufio.NewWriter(conn)
+
+	for _, sample := range samples {
+		out.WriteString(sink.Prefix + sample.Name)
+
+		for _, label := range sample.Labels {
+			// Graphite rejects empty tag values
+			if label.GetValue() == "" {
+				continue
+			}
+
+			out.WriteString(";" + label.GetName() + "=" + graphiteReplacer.Replace(label.GetValue()))
+		}
+
+		out.WriteString(" " + strconv.FormatFloat(sample.Value, 'f', -1, 64) + timestamp)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sample is a single value from a gathered metric family, flattened as in the text exposition format
+type Sample struct {
+	Name   string
+	Labels []*dto.LabelPair
+	Type   dto.MetricType
+	Value  float64
+}
+
+// Samples flattens gathered metric families. Histograms and summaries produce _bucket or quantile,
+// _sum and _count samples. NaN values are dropped
+func Samples(families []*dto.MetricFamily) []Sample {
+	var samples []Sample
+
+	add := func(name string, labels []*dto.LabelPair, kind dto.MetricType, value float64) {
+		if !math.IsNaN(value) {
+			samples = append(samples, Sample{name, labels, kind, value})
+		}
+	}
+
+	for _, family := range families {
+		name := family.GetName()
+		kind := family.GetType()
+
+		for _, m := range family.Metric {
+			switch kind {
+			case dto.MetricType_COUNTER:
+				add(name, m.Label, kind, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.Label, kind, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.Label, kind, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				hist := m.GetHistogram()
+				for _, bucket := range hist.Bucket {
+					add(name+"_bucket", withLabel(m.Label, "le", strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)), kind, float64(bucket.GetCumulativeCount()))
+				}
+
+				add(name+"_bucket", withLabel(m.Label, "le", "+Inf"), kind, float64(hist.GetSampleCount()))
+				add(name+"_sum", m.Label, kind, hist.GetSampleSum())
+				add(name+"_count", m.Label, kind, float64(hist.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.Quantile {
+					add(name, withLabel(m.Label, "quantile", strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)), kind, q.GetValue())
+				}
+
+				add(name+"_sum", m.Label, kind, summary.GetSampleSum())
+				add(name+"_count", m.Label, kind, float64(summary.GetSampleCount()))
+			}
+		}
+	}
+
+	return samples
+}
+
+func withLabel(labels []*dto.LabelPair, name, value string) []*dto.LabelPair {
+	copied := make([]*dto.LabelPair, len(labels), len(labels)+1)
+	copy(copied, labels)
+
+	return append(copied, &dto.LabelPair{Name: &name, Value: &value})
+}
+
+// Key identifies a sample's series
+func (sample Sample) Key() string {
+	key := sample.Name
+	for _, label := range sample.Labels {
+		key += "\xff" + label.GetName() + "\xff" + label.GetValue()
+	}
+
+	return key
+}
+
+// Flusher sends gathered samples to a sink
+type Flusher interface {
+	Flush(samples []Sample, now time.Time) error
+}
+
+// Run gathers samples from gatherer and flushes them every interval until the context is canceled.
+// Errors are passed to onError
+func Run(ctx context.Context, gatherer prometheus.Gatherer, flusher Flusher, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		families, err := gatherer.Gather()
+		if err != nil {
+			onError(err)
+		}
+
+		err = flusher.Flush(Samples(families), time.Now())
+		if err != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package sink
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MaxDatagramSize limits StatsD packets to fit in a typical network MTU
+const MaxDatagramSize = 1432
+
+// StatsD sends gauges and counters to a StatsD server over UDP. Labels are sent as DogStatsD tags,
+// which are supported by Datadog, Telegraf and statsd_exporter. Histograms and summaries are not sent
+type StatsD struct {
+	Addr   string
+	Prefix string
+
+	// Counters are sent as increments since the previous flush
+	previous map[string]float64
+}
+
+// NewStatsD creates a StatsD sink for a host:port address. Metric names are prefixed with prefix, if set
+func NewStatsD(addr, prefix string) *StatsD {
+	return &StatsD{Addr: addr, Prefix: prefix, previous: map[string]float64{}}
+}
+
+var statsdReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// Flush sends samples in as few datagrams as fit
+func (sink *StatsD) Flush(samples []Sample, _ time.Time) error {
+	conn, err := net.Dial("udp", sink.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	var line bytes.Buffer
+
+	for _, sample := range samples {
+		line.Reset()
+
+		switch sample.Type {
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			line.WriteString(sink.Prefix + sample.Name + ":" + strconv.FormatFloat(sample.Value, 'f', -1, 64) + "|g")
+		case dto.MetricType_COUNTER:
+			key := sample.Key()
+			last, seen := sink.previous[key]
+			sink.previous[key] = sample.Value
+
+			// The first flush establishes a baseline, and a decrease means that the counter was reset
+			if !seen || sample.Value < last {
+				continue
+			}
+
+			line.WriteString(sink.Prefix + sample.Name + ":" + strconv.FormatFloat(sample.Value-last, 'f', -1, 64) + "|c")
+		default:
+			continue
+		}
+
+		for i, label := range sample.Labels {
+			if i == 0 {
+				line.WriteString("|#")
+			} else {
+				line.WriteByte(',')
+			}
+
+			line.WriteString(label.GetName() + ":" + statsdReplacer.Replace(label.GetValue()))
+		}
+
+		if packet.Len() > 0 && packet.Len()+1+line.Len() > MaxDatagramSize {
+			_, err = conn.Write(packet.Bytes())
+			if err != nil {
+				return err
+			}
+
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.Write(line.Bytes())
+	}
+
+	if packet.Len() > 0 {
+		_, err = conn.Write(packet.Bytes())
+	}
+
+	return err
+}
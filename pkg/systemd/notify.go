@@ -0,0 +1,53 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends a state message to the service manager's notification socket. It
+// is a no-op when the process was not started with a NOTIFY_SOCKET.
+func Notify(state string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+
+	// Abstract namespace sockets are addressed with a leading '@'
+	addr := &net.UnixAddr{Name: path, Net: "unixgram"}
+	if path[0] == '@' {
+		addr.Name = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns the interval requested by the service manager's
+// WatchdogSec setting, or zero if the watchdog is not enabled for this process
+func WatchdogInterval() (time.Duration, error) {
+	value := os.Getenv("WATCHDOG_USEC")
+	if value == "" {
+		return 0, nil
+	}
+
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	// The watchdog is addressed to a specific process
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
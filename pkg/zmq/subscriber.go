@@ -0,0 +1,207 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Frame flags
+const (
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+)
+
+// MaxFrameSize limits the size of frames read from a publisher. bitcoind's largest messages are raw blocks
+const MaxFrameSize = 16 << 20
+
+// Subscriber is a minimal ZMTP 3.0 SUB socket, sufficient to receive bitcoind's -zmqpub* notifications
+// with the NULL security mechanism. See https://rfc.zeromq.org/spec/23/
+type Subscriber struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Dial connects to a publisher at a tcp://host:port or host:port address and subscribes to topics
+func Dial(ctx context.Context, addr string, topics ...string) (*Subscriber, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", strings.TrimPrefix(addr, "tcp://"))
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscriber{conn, bufio.NewReader(conn)}
+
+	// Abort the handshake if the context is canceled
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	err = sub.handshake()
+	if err == nil {
+		for _, topic := range topics {
+			// ZMTP 3.0 subscriptions are messages prefixed with 0x01
+			err = sub.send(0, append([]byte{1}, topic...))
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		conn.Close()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// handshake exchanges greetings and READY commands with the publisher
+func (sub *Subscriber) handshake() error {
+	greeting := make([]byte, 64)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3 // Version 3.0
+	copy(greeting[12:], "NULL")
+
+	_, err := sub.conn.Write(greeting)
+	if err != nil {
+		return err
+	}
+
+	remote := make([]byte, 64)
+	_, err = io.ReadFull(sub.reader, remote)
+	if err != nil {
+		return err
+	}
+
+	if remote[0] != 0xff || remote[9] != 0x7f || remote[10] < 3 {
+		return errors.New("zmq: unsupported greeting from publisher")
+	}
+
+	if mechanism := string(bytes.TrimRight(remote[12:32], "\x00")); mechanism != "NULL" {
+		return fmt.Errorf("zmq: unsupported security mechanism %q", mechanism)
+	}
+
+	err = sub.send(flagCommand, ready("SUB"))
+	if err != nil {
+		return err
+	}
+
+	flags, body, err := sub.frame()
+	if err != nil {
+		return err
+	}
+
+	if flags&flagCommand == 0 || len(body) < 6 || string(body[1:6]) != "READY" {
+		return errors.New("zmq: expected READY command from publisher")
+	}
+
+	return nil
+}
+
+// ready encodes a READY command body with a Socket-Type property
+func ready(socketType string) []byte {
+	var body bytes.Buffer
+
+	body.WriteByte(5)
+	body.WriteString("READY")
+	body.WriteByte(11)
+	body.WriteString("Socket-Type")
+	binary.Write(&body, binary.BigEndian, uint32(len(socketType)))
+	body.WriteString(socketType)
+
+	return body.Bytes()
+}
+
+// send writes a single frame
+func (sub *Subscriber) send(flags byte, body []byte) error {
+	var header []byte
+
+	if len(body) > 255 {
+		header = make([]byte, 9)
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+
+	_, err := sub.conn.Write(append(header, body...))
+	return err
+}
+
+// frame reads a single frame
+func (sub *Subscriber) frame() (flags byte, body []byte, err error) {
+	flags, err = sub.reader.ReadByte()
+	if err != nil {
+		return
+	}
+
+	var size uint64
+	if flags&flagLong != 0 {
+		err = binary.Read(sub.reader, binary.BigEndian, &size)
+	} else {
+		var short byte
+		short, err = sub.reader.ReadByte()
+		size = uint64(short)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if size > MaxFrameSize {
+		err = fmt.Errorf("zmq: frame size %d exceeds limit", size)
+		return
+	}
+
+	body = make([]byte, size)
+	_, err = io.ReadFull(sub.reader, body)
+	return
+}
+
+// Receive reads the next multipart message. Commands from the publisher are skipped
+func (sub *Subscriber) Receive() ([][]byte, error) {
+	var parts [][]byte
+
+	for {
+		flags, body, err := sub.frame()
+		if err != nil {
+			return nil, err
+		}
+
+		if flags&flagCommand != 0 {
+			continue
+		}
+
+		parts = append(parts, body)
+		if flags&flagMore == 0 {
+			return parts, nil
+		}
+	}
+}
+
+// Close the connection to the publisher
+func (sub *Subscriber) Close() error {
+	return sub.conn.Close()
+}
@@ -0,0 +1,284 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// publisher is the publisher's end of a connection from a Subscriber
+type publisher struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// listen accepts a single connection on a loopback listener and passes it to serve
+func listen(t *testing.T, serve func(pub *publisher)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		serve(&publisher{conn, bufio.NewReader(conn)})
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+// greet exchanges greetings with a subscriber, offering a security mechanism
+func (pub *publisher) greet(mechanism string) ([]byte, error) {
+	greeting := make([]byte, 64)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3
+	greeting[11] = 1
+	copy(greeting[12:], mechanism)
+
+	_, err := pub.conn.Write(greeting)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := make([]byte, 64)
+	_, err = io.ReadFull(pub.reader, remote)
+
+	return remote, err
+}
+
+// handshake completes a NULL handshake and returns the subscriber's READY command and subscriptions
+func (pub *publisher) handshake(topics int) (ready []byte, subscriptions []string, err error) {
+	_, err = pub.greet("NULL")
+	if err != nil {
+		return
+	}
+
+	_, ready, err = pub.read()
+	if err != nil {
+		return
+	}
+
+	err = pub.write(flagCommand, []byte("\x05READY\x0bSocket-Type\x00\x00\x00\x03PUB"))
+	if err != nil {
+		return
+	}
+
+	for len(subscriptions) < topics {
+		var body []byte
+		_, body, err = pub.read()
+		if err != nil {
+			return
+		}
+
+		subscriptions = append(subscriptions, string(body))
+	}
+
+	return
+}
+
+// write sends a frame, with a long size if the body does not fit in a short frame
+func (pub *publisher) write(flags byte, body []byte) error {
+	header := []byte{flags, byte(len(body))}
+	if len(body) > 255 {
+		header = make([]byte, 9)
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+	}
+
+	_, err := pub.conn.Write(append(header, body...))
+	return err
+}
+
+// read receives a frame from the subscriber
+func (pub *publisher) read() (flags byte, body []byte, err error) {
+	flags, err = pub.reader.ReadByte()
+	if err != nil {
+		return
+	}
+
+	var size uint64
+	if flags&flagLong != 0 {
+		err = binary.Read(pub.reader, binary.BigEndian, &size)
+	} else {
+		var short byte
+		short, err = pub.reader.ReadByte()
+		size = uint64(short)
+	}
+
+	if err != nil {
+		return
+	}
+
+	body = make([]byte, size)
+	_, err = io.ReadFull(pub.reader, body)
+	return
+}
+
+func TestSubscriber(t *testing.T) {
+	block := bytes.Repeat([]byte{0xab}, 1000)
+
+	type handshake struct {
+		ready         []byte
+		subscriptions []string
+		err           error
+	}
+	result := make(chan handshake, 1)
+
+	addr := listen(t, func(pub *publisher) {
+		ready, subscriptions, err := pub.handshake(2)
+		result <- handshake{ready, subscriptions, err}
+		if err != nil {
+			return
+		}
+
+		// A command, which is skipped, then a short single part message and a multipart message with a long frame
+		pub.write(flagCommand, []byte("\x04PING\x00\x00"))
+		pub.write(0, []byte("hello"))
+		pub.write(flagMore, []byte("rawblock"))
+		pub.write(flagMore, block)
+		pub.write(0, []byte{1, 0, 0, 0})
+
+		// Wait for the subscriber to close the connection
+		io.Copy(io.Discard, pub.reader)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := Dial(ctx, addr, "sequence", "rawblock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	shake := <-result
+	if shake.err != nil {
+		t.Fatalf("publisher handshake failed: %v", shake.err)
+	}
+
+	if !bytes.Equal(shake.ready, ready("SUB")) {
+		t.Errorf("READY command = %q", shake.ready)
+	}
+
+	if len(shake.subscriptions) != 2 || shake.subscriptions[0] != "\x01sequence" || shake.subscriptions[1] != "\x01rawblock" {
+		t.Errorf("subscriptions = %q", shake.subscriptions)
+	}
+
+	parts, err := sub.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 1 || string(parts[0]) != "hello" {
+		t.Errorf("first message = %q, expected hello", parts)
+	}
+
+	parts, err = sub.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 3 || string(parts[0]) != "rawblock" || !bytes.Equal(parts[1], block) || !bytes.Equal(parts[2], []byte{1, 0, 0, 0}) {
+		t.Errorf("multipart message has %d parts, expected rawblock, a %d byte block and a sequence number", len(parts), len(block))
+	}
+}
+
+func TestSubscriberLongTopic(t *testing.T) {
+	topic := strings.Repeat("t", 300)
+	subscriptions := make(chan []string, 1)
+
+	addr := listen(t, func(pub *publisher) {
+		_, topics, _ := pub.handshake(1)
+		subscriptions <- topics
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := Dial(ctx, addr, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// Subscriptions that do not fit in a short frame are sent in a long frame
+	if topics := <-subscriptions; len(topics) != 1 || topics[0] != "\x01"+topic {
+		t.Errorf("long subscription was not received")
+	}
+}
+
+func TestSubscriberMaxFrameSize(t *testing.T) {
+	addr := listen(t, func(pub *publisher) {
+		_, _, err := pub.handshake(1)
+		if err != nil {
+			return
+		}
+
+		header := make([]byte, 9)
+		header[0] = flagLong
+		binary.BigEndian.PutUint64(header[1:], MaxFrameSize+1)
+		pub.conn.Write(header)
+
+		io.Copy(io.Discard, pub.reader)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := Dial(ctx, addr, "sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	_, err = sub.Receive()
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("Receive returned %v, expected a frame size error", err)
+	}
+}
+
+func TestSubscriberMechanism(t *testing.T) {
+	addr := listen(t, func(pub *publisher) {
+		pub.greet("CURVE")
+		io.Copy(io.Discard, pub.reader)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Dial(ctx, addr, "sequence")
+	if err == nil || !strings.Contains(err.Error(), "CURVE") {
+		t.Errorf("Dial returned %v, expected an unsupported mechanism error", err)
+	}
+}
+
+func TestSubscriberHandshakeCanceled(t *testing.T) {
+	// The publisher never answers the greeting
+	addr := listen(t, func(pub *publisher) {
+		io.Copy(io.Discard, pub.reader)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := Dial(ctx, addr, "sequence")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dial returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
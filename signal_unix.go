@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals log a dump of the exporter's runtime state
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
+
+// reloadSignals reload collectors for the node's current version and RPC methods
+var reloadSignals = []os.Signal{syscall.SIGHUP}
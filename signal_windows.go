@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// dumpSignals log a dump of the exporter's runtime state. Windows has no SIGUSR1
+var dumpSignals = []os.Signal{}
+
+// reloadSignals reload collectors for the node's current version and RPC methods. Windows has no SIGHUP
+var reloadSignals = []os.Signal{}